@@ -1,33 +1,272 @@
 package etcd
 
 import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/DeltaNicola/infralib/logger"
+	"go.etcd.io/etcd/client/pkg/v3/transport"
 	clientv3 "go.etcd.io/etcd/client/v3"
 	"go.uber.org/zap"
 )
 
+// DefaultClient is the name of the client returned by GetEtcdClient and
+// used by the endpoint functions.
+const DefaultClient = "default"
+
 var (
-	etcdClient *clientv3.Client
-	once       sync.Once
+	clientsMu sync.RWMutex
+	clients   = map[string]*clientv3.Client{}
+	once      sync.Once
 )
 
 type InitEndpoint struct {
 	Init bool `yaml:"init"`
 }
 
-func NewEtcdClient(endpoints []string) {
-	once.Do(func() {
+type ClientConfig struct {
+	Endpoints   []string
+	DialTimeout time.Duration
+
+	Username string
+	Password string
+
+	TLS       TLSConfig
+	TLSConfig *tls.Config // Takes precedence over TLS when set
+
+	KeepAliveTime    time.Duration
+	KeepAliveTimeout time.Duration
+	// AutoSyncInterval refreshes the endpoints from the cluster members,
+	// zero disables it.
+	AutoSyncInterval time.Duration
+}
+
+type TLSConfig struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	InsecureSkipVerify bool
+}
+
+func (c TLSConfig) enabled() bool {
+	return c.CAFile != "" || c.CertFile != "" || c.InsecureSkipVerify
+}
+
+// ClientConfigFromEnv reads the config of the client called name from the
+// ETCD_* variables, e.g. ETCD_ENDPOINTS, or from the ETCD_<NAME>_* ones for
+// clients other than DefaultClient. Invalid values are reported in the
+// error, the returned config then uses the defaults for them.
+func ClientConfigFromEnv(name string) (ClientConfig, error) {
+	prefix := "ETCD_"
+	if name != DefaultClient {
+		prefix += strings.ToUpper(name) + "_"
+	}
+
+	config := ClientConfig{
+		Username: os.Getenv(prefix + "USERNAME"),
+		Password: os.Getenv(prefix + "PASSWORD"),
+		TLS: TLSConfig{
+			CAFile:   os.Getenv(prefix + "CA_FILE"),
+			CertFile: os.Getenv(prefix + "CERT_FILE"),
+			KeyFile:  os.Getenv(prefix + "KEY_FILE"),
+		},
+	}
+	for _, endpoint := range strings.Split(os.Getenv(prefix+"ENDPOINTS"), ",") {
+		if endpoint = strings.TrimSpace(endpoint); endpoint != "" {
+			config.Endpoints = append(config.Endpoints, endpoint)
+		}
+	}
+
+	var errs []error
+	durations := []struct {
+		name   string
+		target *time.Duration
+		value  time.Duration
+	}{
+		{"DIAL_TIMEOUT", &config.DialTimeout, 5 * time.Second},
+		{"KEEPALIVE_TIME", &config.KeepAliveTime, 0},
+		{"KEEPALIVE_TIMEOUT", &config.KeepAliveTimeout, 0},
+		{"AUTO_SYNC_INTERVAL", &config.AutoSyncInterval, 0},
+	}
+	for _, duration := range durations {
+		*duration.target = duration.value
+		if value := os.Getenv(prefix + duration.name); value != "" {
+			parsed, err := time.ParseDuration(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s%s non valido: %v", prefix, duration.name, err))
+				continue
+			}
+			*duration.target = parsed
+		}
+	}
+
+	if value := os.Getenv(prefix + "INSECURE_SKIP_VERIFY"); value != "" {
+		insecure, err := strconv.ParseBool(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%sINSECURE_SKIP_VERIFY non valido: %v", prefix, err))
+		}
+		config.TLS.InsecureSkipVerify = insecure
+	}
+
+	if len(config.Endpoints) == 0 {
+		errs = append(errs, fmt.Errorf("%sENDPOINTS non impostato", prefix))
+	}
+
+	return config, errors.Join(errs...)
+}
+
+// NewClient creates a client of the cluster described by config. It is not
+// registered, see RegisterClient.
+func NewClient(config ClientConfig) (*clientv3.Client, error) {
+	if len(config.Endpoints) == 0 {
+		return nil, fmt.Errorf("nessun endpoint ETCD configurato")
+	}
+
+	tlsConfig := config.TLSConfig
+	if tlsConfig == nil && config.TLS.enabled() {
+		info := transport.TLSInfo{
+			TrustedCAFile:      config.TLS.CAFile,
+			CertFile:           config.TLS.CertFile,
+			KeyFile:            config.TLS.KeyFile,
+			InsecureSkipVerify: config.TLS.InsecureSkipVerify,
+		}
+
 		var err error
+		if tlsConfig, err = info.ClientConfig(); err != nil {
+			return nil, fmt.Errorf("errore configurazione TLS ETCD: %w", err)
+		}
+	}
 
-		config := clientv3.Config{
-			Endpoints:   endpoints,
-			DialTimeout: 5 * time.Second,
+	dialTimeout := config.DialTimeout
+	if dialTimeout == 0 {
+		dialTimeout = 5 * time.Second
+	}
+
+	client, err := clientv3.New(clientv3.Config{
+		Endpoints:            config.Endpoints,
+		DialTimeout:          dialTimeout,
+		DialKeepAliveTime:    config.KeepAliveTime,
+		DialKeepAliveTimeout: config.KeepAliveTimeout,
+		AutoSyncInterval:     config.AutoSyncInterval,
+		Username:             config.Username,
+		Password:             config.Password,
+		TLS:                  tlsConfig,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("errore connessione a ETCD %v: %w", config.Endpoints, err)
+	}
+
+	return client, nil
+}
+
+// RegisterClient creates a client of the cluster described by config and makes
+// it available to GetClient under name. Registering DefaultClient sets the
+// client used by GetEtcdClient and the endpoint functions.
+func RegisterClient(name string, config ClientConfig) (*clientv3.Client, error) {
+	if GetClient(name) != nil {
+		return nil, fmt.Errorf("client ETCD %s già registrato", name)
+	}
+
+	client, err := NewClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	clientsMu.Lock()
+	if _, exists := clients[name]; exists {
+		clientsMu.Unlock()
+		client.Close()
+		return nil, fmt.Errorf("client ETCD %s già registrato", name)
+	}
+	clients[name] = client
+	clientsMu.Unlock()
+
+	logger.Logger.Info(
+		"ETCD Client Connected",
+		zap.String("client", name),
+		zap.Strings("endpoints", config.Endpoints),
+	)
+
+	return client, nil
+}
+
+// RegisterClientFromEnv registers the client called name with the config
+// read by ClientConfigFromEnv.
+func RegisterClientFromEnv(name string) (*clientv3.Client, error) {
+	config, err := ClientConfigFromEnv(name)
+	if err != nil {
+		return nil, err
+	}
+	return RegisterClient(name, config)
+}
+
+// GetClient returns the client registered under name, nil if there is none.
+func GetClient(name string) *clientv3.Client {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+
+	return clients[name]
+}
+
+// ClientNames returns the names of the registered clients, sorted.
+func ClientNames() []string {
+	clientsMu.RLock()
+	defer clientsMu.RUnlock()
+
+	names := make([]string, 0, len(clients))
+	for name := range clients {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// CloseClient closes the client registered under name and removes it from
+// the registry.
+func CloseClient(name string) error {
+	clientsMu.Lock()
+	client, exists := clients[name]
+	delete(clients, name)
+	clientsMu.Unlock()
+
+	if !exists {
+		return fmt.Errorf("client ETCD %s non registrato", name)
+	}
+
+	if err := client.Close(); err != nil {
+		return fmt.Errorf("errore chiusura client ETCD %s: %w", name, err)
+	}
+	return nil
+}
+
+// CloseClients closes every registered client.
+func CloseClients() error {
+	var errs []error
+	for _, name := range ClientNames() {
+		if err := CloseClient(name); err != nil {
+			errs = append(errs, err)
 		}
+	}
+	return errors.Join(errs...)
+}
 
-		etcdClient, err = clientv3.New(config)
+// NewEtcdClient registers DefaultClient once, exiting the process when the
+// connection fails.
+//
+// Deprecated: use RegisterClient or RegisterClientFromEnv with DefaultClient.
+func NewEtcdClient(endpoints []string) {
+	once.Do(func() {
+		_, err := RegisterClient(DefaultClient, ClientConfig{
+			Endpoints:   endpoints,
+			DialTimeout: 5 * time.Second,
+		})
 		if err != nil {
 			logger.Logger.Fatal(
 				"Error Connecting To ETCD",
@@ -38,13 +277,13 @@ func NewEtcdClient(endpoints []string) {
 }
 
 func GetEtcdClient() *clientv3.Client {
-	return etcdClient
+	return GetClient(DefaultClient)
 }
 
 func CloseEtcdClient() {
-	if etcdClient != nil {
+	if GetEtcdClient() != nil {
 
-		err := etcdClient.Close()
+		err := CloseClient(DefaultClient)
 		if err != nil {
 			logger.Logger.Error(
 				"Error Closing ETCD Client",
@@ -0,0 +1,152 @@
+package etcd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClientConfigFromEnv(t *testing.T) {
+	tests := []struct {
+		name   string
+		client string
+		env    map[string]string
+		want   ClientConfig
+		errs   []string
+	}{
+		{
+			name:   "default client",
+			client: DefaultClient,
+			env: map[string]string{
+				"ETCD_ENDPOINTS":    "http://a:2379, http://b:2379,",
+				"ETCD_DIAL_TIMEOUT": "2s",
+				"ETCD_USERNAME":     "orders",
+			},
+			want: ClientConfig{
+				Endpoints:   []string{"http://a:2379", "http://b:2379"},
+				DialTimeout: 2 * time.Second,
+				Username:    "orders",
+			},
+		},
+		{
+			name:   "named client",
+			client: "config",
+			env: map[string]string{
+				"ETCD_ENDPOINTS":                    "http://default:2379",
+				"ETCD_CONFIG_ENDPOINTS":             "http://config:2379",
+				"ETCD_CONFIG_AUTO_SYNC_INTERVAL":    "1m",
+				"ETCD_CONFIG_INSECURE_SKIP_VERIFY":  "true",
+				"ETCD_CONFIG_KEEPALIVE_TIME":        "30s",
+				"ETCD_CONFIG_KEEPALIVE_TIMEOUT":     "10s",
+				"ETCD_CONFIG_DIAL_TIMEOUT":          "",
+				"ETCD_CONFIG_CA_FILE":               "/etc/ca.pem",
+				"ETCD_CONFIG_CERT_FILE":             "/etc/cert.pem",
+				"ETCD_CONFIG_KEY_FILE":              "/etc/key.pem",
+				"ETCD_CONFIG_PASSWORD":              "secret",
+				"ETCD_CONFIG_USERNAME":              "config",
+				"ETCD_CONFIG_UNRELATED_SETTING_XYZ": "ignored",
+			},
+			want: ClientConfig{
+				Endpoints:        []string{"http://config:2379"},
+				DialTimeout:      5 * time.Second,
+				Username:         "config",
+				Password:         "secret",
+				TLS:              TLSConfig{CAFile: "/etc/ca.pem", CertFile: "/etc/cert.pem", KeyFile: "/etc/key.pem", InsecureSkipVerify: true},
+				KeepAliveTime:    30 * time.Second,
+				KeepAliveTimeout: 10 * time.Second,
+				AutoSyncInterval: time.Minute,
+			},
+		},
+		{
+			name:   "missing endpoints",
+			client: DefaultClient,
+			want:   ClientConfig{DialTimeout: 5 * time.Second},
+			errs:   []string{"ETCD_ENDPOINTS non impostato"},
+		},
+		{
+			name:   "invalid values",
+			client: "orders",
+			env: map[string]string{
+				"ETCD_ORDERS_ENDPOINTS":            "http://orders:2379",
+				"ETCD_ORDERS_DIAL_TIMEOUT":         "5",
+				"ETCD_ORDERS_INSECURE_SKIP_VERIFY": "maybe",
+			},
+			want: ClientConfig{
+				Endpoints:   []string{"http://orders:2379"},
+				DialTimeout: 5 * time.Second,
+			},
+			errs: []string{"ETCD_ORDERS_DIAL_TIMEOUT non valido", "ETCD_ORDERS_INSECURE_SKIP_VERIFY non valido"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, name := range []string{"ETCD_ENDPOINTS", "ETCD_DIAL_TIMEOUT", "ETCD_USERNAME"} {
+				t.Setenv(name, "")
+			}
+			for name, value := range tt.env {
+				t.Setenv(name, value)
+			}
+
+			got, err := ClientConfigFromEnv(tt.client)
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+			if len(tt.errs) == 0 && err != nil {
+				t.Errorf("got error %v", err)
+			}
+			for _, want := range tt.errs {
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("got error %v, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestNewClientErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		config ClientConfig
+		want   string
+	}{
+		{"no endpoints", ClientConfig{}, "nessun endpoint ETCD configurato"},
+		{
+			"missing certificate",
+			ClientConfig{Endpoints: []string{"https://localhost:2379"}, TLS: TLSConfig{CertFile: "/nonexistent/cert.pem", KeyFile: "/nonexistent/key.pem"}},
+			"errore configurazione TLS ETCD",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(tt.config)
+			if client != nil {
+				client.Close()
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("got error %v, want it to contain %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegisterClientErrors(t *testing.T) {
+	if _, err := RegisterClient("orders", ClientConfig{}); err == nil || err.Error() != "nessun endpoint ETCD configurato" {
+		t.Errorf("got error %v, want the NewClient error", err)
+	}
+	if GetClient("orders") != nil {
+		t.Error("a client failing to connect was registered")
+	}
+
+	t.Setenv("ETCD_ORDERS_ENDPOINTS", "")
+	if _, err := RegisterClientFromEnv("orders"); err == nil || !strings.Contains(err.Error(), "ETCD_ORDERS_ENDPOINTS non impostato") {
+		t.Errorf("got error %v, want the ClientConfigFromEnv error", err)
+	}
+
+	if err := CloseClient("orders"); err == nil || err.Error() != "client ETCD orders non registrato" {
+		t.Errorf("got error %v, want client not registered", err)
+	}
+}
@@ -0,0 +1,100 @@
+package etcd
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v3"
+)
+
+// Codec converts the values of Get, Put and List to and from the bytes
+// stored in etcd.
+type Codec interface {
+	Marshal(v interface{}) ([]byte, error)
+	Unmarshal(data []byte, v interface{}) error
+}
+
+var (
+	JSON Codec = jsonCodec{}
+	YAML Codec = yamlCodec{}
+	// Protobuf works with generated message types, e.g. Get[*pb.Config].
+	Protobuf Codec = protobufCodec{}
+	// Raw stores []byte and string values as they are.
+	Raw Codec = rawCodec{}
+)
+
+type jsonCodec struct{}
+
+func (jsonCodec) Marshal(v interface{}) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+func (jsonCodec) Unmarshal(data []byte, v interface{}) error {
+	return json.Unmarshal(data, v)
+}
+
+type yamlCodec struct{}
+
+func (yamlCodec) Marshal(v interface{}) ([]byte, error) {
+	return yaml.Marshal(v)
+}
+
+func (yamlCodec) Unmarshal(data []byte, v interface{}) error {
+	return yaml.Unmarshal(data, v)
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) Marshal(v interface{}) ([]byte, error) {
+	message, ok := v.(proto.Message)
+	if !ok {
+		return nil, fmt.Errorf("il valore %T non è un proto.Message", v)
+	}
+	return proto.Marshal(message)
+}
+
+func (protobufCodec) Unmarshal(data []byte, v interface{}) error {
+	message, ok := v.(proto.Message)
+	if !ok {
+		// v points to a nil message pointer, like the zero value of a
+		// *pb.Config type parameter
+		target := reflect.ValueOf(v)
+		if target.Kind() != reflect.Ptr || target.Elem().Kind() != reflect.Ptr {
+			return fmt.Errorf("il valore %T non è un proto.Message", v)
+		}
+		if target.Elem().IsNil() {
+			target.Elem().Set(reflect.New(target.Elem().Type().Elem()))
+		}
+		if message, ok = target.Elem().Interface().(proto.Message); !ok {
+			return fmt.Errorf("il valore %T non è un proto.Message", v)
+		}
+	}
+	return proto.Unmarshal(data, message)
+}
+
+type rawCodec struct{}
+
+func (rawCodec) Marshal(v interface{}) ([]byte, error) {
+	switch value := v.(type) {
+	case []byte:
+		return value, nil
+	case string:
+		return []byte(value), nil
+	default:
+		return nil, fmt.Errorf("il codec raw supporta solo []byte e string, non %T", v)
+	}
+}
+
+func (rawCodec) Unmarshal(data []byte, v interface{}) error {
+	switch value := v.(type) {
+	case *[]byte:
+		*value = append([]byte(nil), data...)
+	case *string:
+		*value = string(data)
+	default:
+		return fmt.Errorf("il codec raw supporta solo []byte e string, non %T", v)
+	}
+	return nil
+}
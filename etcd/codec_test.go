@@ -0,0 +1,134 @@
+package etcd
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type testConfig struct {
+	Name     string   `json:"name" yaml:"name"`
+	Replicas int      `json:"replicas" yaml:"replicas"`
+	Tags     []string `json:"tags" yaml:"tags"`
+}
+
+func TestCodecRoundTrip(t *testing.T) {
+	config := testConfig{Name: "orders", Replicas: 3, Tags: []string{"a", "b"}}
+
+	tests := []struct {
+		name   string
+		codec  Codec
+		value  interface{}
+		target func() interface{}
+		data   string
+	}{
+		{"json", JSON, config, func() interface{} { return &testConfig{} }, `{"name":"orders","replicas":3,"tags":["a","b"]}`},
+		{"yaml", YAML, config, func() interface{} { return &testConfig{} }, "name: orders\nreplicas: 3\ntags:\n    - a\n    - b\n"},
+		{"raw bytes", Raw, []byte("v1"), func() interface{} { return &[]byte{} }, "v1"},
+		{"raw string", Raw, "v1", func() interface{} { var s string; return &s }, "v1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := tt.codec.Marshal(tt.value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.data {
+				t.Errorf("marshaled %q, want %q", data, tt.data)
+			}
+
+			target := tt.target()
+			if err := tt.codec.Unmarshal(data, target); err != nil {
+				t.Fatal(err)
+			}
+			if got := reflect.ValueOf(target).Elem().Interface(); !reflect.DeepEqual(got, tt.value) {
+				t.Errorf("unmarshaled %#v, want %#v", got, tt.value)
+			}
+		})
+	}
+}
+
+func TestProtobufCodec(t *testing.T) {
+	data, err := Protobuf.Marshal(wrapperspb.String("orders"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Get[*wrapperspb.StringValue] decodes into a nil message pointer
+	var message *wrapperspb.StringValue
+	if err := Protobuf.Unmarshal(data, &message); err != nil {
+		t.Fatal(err)
+	}
+	if message.GetValue() != "orders" {
+		t.Errorf("got %q, want orders", message.GetValue())
+	}
+
+	existing := &wrapperspb.StringValue{}
+	if err := Protobuf.Unmarshal(data, existing); err != nil {
+		t.Fatal(err)
+	}
+	if !proto.Equal(existing, message) {
+		t.Errorf("got %v, want %v", existing, message)
+	}
+}
+
+func TestCodecErrors(t *testing.T) {
+	var s string
+	var n int
+
+	tests := []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{"raw marshal", func() error { _, err := Raw.Marshal(42); return err }, "il codec raw supporta solo []byte e string, non int"},
+		{"raw unmarshal", func() error { return Raw.Unmarshal([]byte("v"), &n) }, "il codec raw supporta solo []byte e string, non *int"},
+		{"protobuf marshal", func() error { _, err := Protobuf.Marshal("v"); return err }, "il valore string non è un proto.Message"},
+		{"protobuf unmarshal", func() error { return Protobuf.Unmarshal(nil, &s) }, "il valore *string non è un proto.Message"},
+		{"protobuf invalid data", func() error { return Protobuf.Unmarshal([]byte{0xff}, &wrapperspb.StringValue{}) }, ""},
+		{"json invalid data", func() error { return JSON.Unmarshal([]byte("{"), &testConfig{}) }, ""},
+		{"yaml invalid data", func() error { return YAML.Unmarshal([]byte("name: [a"), &testConfig{}) }, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			if tt.want != "" && err.Error() != tt.want {
+				t.Errorf("got %q, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestDecode(t *testing.T) {
+	kv := &mvccpb.KeyValue{
+		Key:            []byte("config/orders"),
+		Value:          []byte(`{"name":"orders","replicas":3}`),
+		CreateRevision: 10,
+		ModRevision:    12,
+		Version:        2,
+		Lease:          7,
+	}
+
+	result, err := decode[testConfig](JSON, kv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Metadata{Key: "config/orders", CreateRevision: 10, ModRevision: 12, Version: 2, Lease: 7}
+	if result.Metadata != want || result.Value.Name != "orders" || result.Value.Replicas != 3 {
+		t.Errorf("got %+v", result)
+	}
+
+	kv.Value = []byte("not json")
+	if _, err := decode[testConfig](JSON, kv); err == nil || !strings.HasPrefix(err.Error(), "errore decodifica chiave config/orders: ") {
+		t.Errorf("got %v, want a decoding error naming the key", err)
+	}
+}
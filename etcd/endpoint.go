@@ -11,10 +11,20 @@ import (
 	"go.uber.org/zap"
 )
 
+// Deprecated: use CreateOrUpdateEndpointContext.
 func CreateOrUpdateEndpoint(key string, value interface{}) error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	return CreateOrUpdateEndpointContext(ctx, key, value)
+}
+
+// CreateOrUpdateEndpointContext writes value as JSON at key. The write is
+// audited without the value, which may hold secrets.
+func CreateOrUpdateEndpointContext(ctx context.Context, key string, value interface{}) error {
 	jsonValue, err := json.Marshal(value)
 	if err != nil {
-		logger.Logger.Error(
+		logger.FromContext(ctx).Error(
 			"Error JSON Conversion",
 			zap.String("key", key),
 			zap.Reflect("value", value),
@@ -24,31 +34,41 @@ func CreateOrUpdateEndpoint(key string, value interface{}) error {
 
 	cli := GetEtcdClient()
 
-	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
-	defer cancel()
-
-	_, err = cli.Put(ctx, key, string(jsonValue))
+	resp, err := cli.Put(ctx, key, string(jsonValue))
 	if err != nil {
-		logger.Logger.Error(
+		logger.FromContext(ctx).Error(
 			"Error Updating ETCD",
 			zap.Error(err),
 		)
 
 		return fmt.Errorf("error updating ETCD %s: %v", key, err)
 	}
-	logger.Logger.Error(
+	logger.FromContext(ctx).Error(
 		"ETCD Updated Successfully",
 		zap.String("key", key),
 		zap.Reflect("value", value),
 	)
+	logger.Audit.Info(
+		"ETCD Endpoint Updated",
+		zap.String("key", key),
+		zap.Int64("revision", resp.Header.Revision),
+	)
 
 	return nil
 }
 
+// Deprecated: use GetEndpointContext, or Get to decode the value into a
+// type.
 func GetEndpoint(key string) (interface{}, error) {
+	return GetEndpointContext(context.Background(), key)
+}
+
+// GetEndpointContext returns a map for JSON objects, the raw string
+// otherwise and nil when the key does not exist.
+func GetEndpointContext(ctx context.Context, key string) (interface{}, error) {
 	client := GetEtcdClient()
 
-	resp, err := client.Get(context.Background(), key)
+	resp, err := client.Get(ctx, key)
 	if err != nil {
 		return "", fmt.Errorf("errore lettura stato ordine: %v", err)
 	}
@@ -67,16 +87,27 @@ func GetEndpoint(key string) (interface{}, error) {
 	return value, nil
 }
 
+// Deprecated: use DeleteEndpointContext.
 func DeleteEndpoint(key string) error {
-	cli := GetEtcdClient()
-
 	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
 	defer cancel()
 
-	_, err := cli.Delete(ctx, key)
+	return DeleteEndpointContext(ctx, key)
+}
+
+func DeleteEndpointContext(ctx context.Context, key string) error {
+	cli := GetEtcdClient()
+
+	resp, err := cli.Delete(ctx, key)
 	if err != nil {
 		return fmt.Errorf("errore eliminazione key %s: %v", key, err)
 	}
 	log.Printf("key %s eliminato con successo", key)
+	logger.Audit.Info(
+		"ETCD Endpoint Deleted",
+		zap.String("key", key),
+		zap.Int64("deleted", resp.Deleted),
+		zap.Int64("revision", resp.Header.Revision),
+	)
 	return nil
 }
@@ -0,0 +1,193 @@
+package etcd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/DeltaNicola/infralib/logger"
+	"go.etcd.io/etcd/api/v3/mvccpb"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+// ErrNotFound is returned by Get when the key does not exist.
+var ErrNotFound = errors.New("chiave non trovata")
+
+// Metadata describes the revision of a key, see mvccpb.KeyValue.
+type Metadata struct {
+	Key            string
+	CreateRevision int64
+	ModRevision    int64
+	Version        int64
+	Lease          clientv3.LeaseID
+}
+
+type KeyValue[T any] struct {
+	Metadata
+	Value T
+}
+
+type kvOptions struct {
+	codec Codec
+	lease clientv3.LeaseID
+}
+
+type KVOption func(*kvOptions)
+
+// WithCodec replaces the default JSON codec.
+func WithCodec(codec Codec) KVOption {
+	return func(o *kvOptions) {
+		o.codec = codec
+	}
+}
+
+// WithLease attaches the key written by Put to a lease.
+func WithLease(lease clientv3.LeaseID) KVOption {
+	return func(o *kvOptions) {
+		o.lease = lease
+	}
+}
+
+func newKVOptions(opts []KVOption) kvOptions {
+	options := kvOptions{codec: JSON}
+	for _, opt := range opts {
+		opt(&options)
+	}
+	return options
+}
+
+func metadataOf(kv *mvccpb.KeyValue) Metadata {
+	return Metadata{
+		Key:            string(kv.Key),
+		CreateRevision: kv.CreateRevision,
+		ModRevision:    kv.ModRevision,
+		Version:        kv.Version,
+		Lease:          clientv3.LeaseID(kv.Lease),
+	}
+}
+
+func decode[T any](codec Codec, kv *mvccpb.KeyValue) (KeyValue[T], error) {
+	result := KeyValue[T]{Metadata: metadataOf(kv)}
+	if err := codec.Unmarshal(kv.Value, &result.Value); err != nil {
+		return KeyValue[T]{}, fmt.Errorf("errore decodifica chiave %s: %w", kv.Key, err)
+	}
+	return result, nil
+}
+
+// Get reads key and decodes its value into T, returning an error wrapping
+// ErrNotFound when the key does not exist.
+func Get[T any](ctx context.Context, client *clientv3.Client, key string, opts ...KVOption) (KeyValue[T], error) {
+	options := newKVOptions(opts)
+
+	resp, err := client.Get(ctx, key)
+	if err != nil {
+		logger.FromContext(ctx).Error(
+			"Error Reading ETCD Key",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return KeyValue[T]{}, fmt.Errorf("errore lettura chiave %s: %w", key, err)
+	}
+
+	if len(resp.Kvs) == 0 {
+		return KeyValue[T]{}, fmt.Errorf("%w: %s", ErrNotFound, key)
+	}
+
+	result, err := decode[T](options.codec, resp.Kvs[0])
+	if err != nil {
+		logger.FromContext(ctx).Error(
+			"Error Decoding ETCD Key",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return KeyValue[T]{}, err
+	}
+
+	return result, nil
+}
+
+// Put encodes value and writes it at key, returning the metadata of the
+// new revision. The write is audited without the value, which may hold
+// secrets.
+func Put[T any](ctx context.Context, client *clientv3.Client, key string, value T, opts ...KVOption) (Metadata, error) {
+	options := newKVOptions(opts)
+
+	data, err := options.codec.Marshal(value)
+	if err != nil {
+		logger.FromContext(ctx).Error(
+			"Error Encoding ETCD Key",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return Metadata{}, fmt.Errorf("errore codifica chiave %s: %w", key, err)
+	}
+
+	putOptions := []clientv3.OpOption{clientv3.WithPrevKV()}
+	if options.lease != clientv3.NoLease {
+		putOptions = append(putOptions, clientv3.WithLease(options.lease))
+	}
+
+	resp, err := client.Put(ctx, key, string(data), putOptions...)
+	if err != nil {
+		logger.FromContext(ctx).Error(
+			"Error Updating ETCD",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return Metadata{}, fmt.Errorf("errore aggiornamento chiave %s: %w", key, err)
+	}
+
+	metadata := Metadata{
+		Key:            key,
+		CreateRevision: resp.Header.Revision,
+		ModRevision:    resp.Header.Revision,
+		Version:        1,
+		Lease:          options.lease,
+	}
+	if resp.PrevKv != nil {
+		metadata.CreateRevision = resp.PrevKv.CreateRevision
+		metadata.Version = resp.PrevKv.Version + 1
+	}
+
+	logger.Audit.Info(
+		"ETCD Key Updated",
+		zap.String("key", key),
+		zap.Int64("revision", metadata.ModRevision),
+		zap.Int64("version", metadata.Version),
+	)
+
+	return metadata, nil
+}
+
+// List reads the keys starting with prefix, sorted by key, and decodes
+// their values into T.
+func List[T any](ctx context.Context, client *clientv3.Client, prefix string, opts ...KVOption) ([]KeyValue[T], error) {
+	options := newKVOptions(opts)
+
+	resp, err := client.Get(ctx, prefix, clientv3.WithPrefix(), clientv3.WithSort(clientv3.SortByKey, clientv3.SortAscend))
+	if err != nil {
+		logger.FromContext(ctx).Error(
+			"Error Reading ETCD Keys",
+			zap.String("prefix", prefix),
+			zap.Error(err),
+		)
+		return nil, fmt.Errorf("errore lettura chiavi %s: %w", prefix, err)
+	}
+
+	results := make([]KeyValue[T], 0, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		result, err := decode[T](options.codec, kv)
+		if err != nil {
+			logger.FromContext(ctx).Error(
+				"Error Decoding ETCD Key",
+				zap.String("key", string(kv.Key)),
+				zap.Error(err),
+			)
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
@@ -9,16 +9,23 @@ import (
 	"go.uber.org/zap"
 )
 
+// Deprecated: use AcquireLockContext.
 func AcquireLock(client *clientv3.Client, lockKey string, ttl int64) (*clientv3.LeaseGrantResponse, error) {
-	logger.Logger.Info(
+	return AcquireLockContext(context.Background(), client, lockKey, ttl)
+}
+
+func AcquireLockContext(ctx context.Context, client *clientv3.Client, lockKey string, ttl int64) (*clientv3.LeaseGrantResponse, error) {
+	log := logger.FromContext(ctx)
+
+	log.Info(
 		"Acquiring Lock",
 		zap.String("lockKey", lockKey),
 		zap.Int64("ttl", ttl),
 	)
 
-	resp, err := client.Get(context.Background(), lockKey)
+	resp, err := client.Get(ctx, lockKey)
 	if err != nil {
-		logger.Logger.Error(
+		log.Error(
 			"Error Checking Lock Key",
 			zap.String("lockKey", lockKey),
 			zap.Error(err),
@@ -27,16 +34,16 @@ func AcquireLock(client *clientv3.Client, lockKey string, ttl int64) (*clientv3.
 	}
 
 	if len(resp.Kvs) > 0 {
-		logger.Logger.Warn(
+		log.Warn(
 			"Lock Key Already Exists",
 			zap.String("lockKey", lockKey),
 		)
 		return nil, fmt.Errorf("la chiave %s è già occupata", lockKey)
 	}
 
-	leaseResp, err := client.Grant(context.Background(), ttl)
+	leaseResp, err := client.Grant(ctx, ttl)
 	if err != nil {
-		logger.Logger.Error(
+		log.Error(
 			"Failed Creating Lease",
 			zap.String("lockKey", lockKey),
 			zap.Error(err),
@@ -44,13 +51,13 @@ func AcquireLock(client *clientv3.Client, lockKey string, ttl int64) (*clientv3.
 		return nil, fmt.Errorf("errore durante la creazione del lease: %v", err)
 	}
 
-	txn := client.Txn(context.Background()).
+	txn := client.Txn(ctx).
 		If(clientv3.Compare(clientv3.CreateRevision(lockKey), "=", 0)).
 		Then(clientv3.OpPut(lockKey, "", clientv3.WithLease(leaseResp.ID)))
 
 	txnResp, err := txn.Commit()
 	if err != nil {
-		logger.Logger.Error(
+		log.Error(
 			"Transaction Error",
 			zap.String("lockKey", lockKey),
 			zap.Error(err),
@@ -59,7 +66,7 @@ func AcquireLock(client *clientv3.Client, lockKey string, ttl int64) (*clientv3.
 	}
 
 	if !txnResp.Succeeded {
-		logger.Logger.Warn(
+		log.Warn(
 			"Lock Acquisition Failed",
 			zap.String("reason", "key already occupied"),
 			zap.String("lockKey", lockKey),
@@ -67,25 +74,39 @@ func AcquireLock(client *clientv3.Client, lockKey string, ttl int64) (*clientv3.
 		return nil, fmt.Errorf("il lock non è stato acquisito, chiave già occupata")
 	}
 
-	logger.Logger.Info(
+	log.Info(
 		"Lock acquired successfully",
 		zap.String("lockKey", lockKey),
 		zap.Reflect("leaseID", leaseResp.ID),
 	)
+	logger.Audit.Info(
+		"Lock Acquired",
+		zap.String("lockKey", lockKey),
+		zap.Reflect("leaseID", leaseResp.ID),
+		zap.Int64("ttl", ttl),
+		zap.Int64("revision", txnResp.Header.Revision),
+	)
 
 	return leaseResp, nil
 }
 
+// Deprecated: use ReleaseOrderLockContext.
 func ReleaseOrderLock(client *clientv3.Client, lockKey string, leaseID clientv3.LeaseID) error {
-	logger.Logger.Info(
+	return ReleaseOrderLockContext(context.Background(), client, lockKey, leaseID)
+}
+
+func ReleaseOrderLockContext(ctx context.Context, client *clientv3.Client, lockKey string, leaseID clientv3.LeaseID) error {
+	log := logger.FromContext(ctx)
+
+	log.Info(
 		"Releasing Lock",
 		zap.String("lockKey", lockKey),
 		zap.Reflect("leaseID", leaseID),
 	)
 
-	resp, err := client.Get(context.Background(), lockKey)
+	resp, err := client.Get(ctx, lockKey)
 	if err != nil {
-		logger.Logger.Error(
+		log.Error(
 			"Error Checking Lock Key",
 			zap.String("lockKey", lockKey),
 			zap.Error(err),
@@ -94,16 +115,16 @@ func ReleaseOrderLock(client *clientv3.Client, lockKey string, leaseID clientv3.
 	}
 
 	if len(resp.Kvs) == 0 {
-		logger.Logger.Warn(
+		log.Warn(
 			"Lock Key Already Deleted",
 			zap.String("lockKey", lockKey),
 		)
 		return fmt.Errorf("la chiave %s non esiste già più", lockKey)
 	}
 
-	_, err = client.Delete(context.Background(), lockKey)
+	_, err = client.Delete(ctx, lockKey)
 	if err != nil {
-		logger.Logger.Error(
+		log.Error(
 			"Error Deleting Lock Key",
 			zap.String("lockKey", lockKey),
 			zap.Error(err),
@@ -111,14 +132,19 @@ func ReleaseOrderLock(client *clientv3.Client, lockKey string, leaseID clientv3.
 		return fmt.Errorf("errore durante la rimozione del lock: %v", err)
 	}
 
-	logger.Logger.Info(
+	log.Info(
 		"Lock Key Deleted Successfully",
 		zap.String("lockKey", lockKey),
 	)
+	logger.Audit.Info(
+		"Lock Released",
+		zap.String("lockKey", lockKey),
+		zap.Reflect("leaseID", leaseID),
+	)
 
-	_, err = client.Revoke(context.Background(), leaseID)
+	_, err = client.Revoke(ctx, leaseID)
 	if err != nil {
-		logger.Logger.Error(
+		log.Error(
 			"Error Revoking Lease",
 			zap.Reflect("leaseID", leaseID),
 			zap.Error(err),
@@ -126,7 +152,7 @@ func ReleaseOrderLock(client *clientv3.Client, lockKey string, leaseID clientv3.
 		return fmt.Errorf("errore durante la revoca del lease: %v", err)
 	}
 
-	logger.Logger.Info(
+	log.Info(
 		"Lease Revoked Successfully",
 		zap.Reflect("leaseID", leaseID),
 	)
@@ -134,7 +160,9 @@ func ReleaseOrderLock(client *clientv3.Client, lockKey string, leaseID clientv3.
 }
 
 func WaitForLockRelease(ctx context.Context, client *clientv3.Client, lockKey string, interfaceAction interface{}, releaseAction func(interface{})) {
-	logger.Logger.Info(
+	log := logger.FromContext(ctx)
+
+	log.Info(
 		"Waiting for lock release",
 		zap.String("lockKey", lockKey),
 	)
@@ -144,7 +172,7 @@ func WaitForLockRelease(ctx context.Context, client *clientv3.Client, lockKey st
 	for {
 		select {
 		case <-ctx.Done():
-			logger.Logger.Warn(
+			log.Warn(
 				"Context canceled while waiting for lock release",
 				zap.String("lockKey", lockKey),
 			)
@@ -152,7 +180,7 @@ func WaitForLockRelease(ctx context.Context, client *clientv3.Client, lockKey st
 		case wresp := <-rch:
 			for _, ev := range wresp.Events {
 				if ev.Type == clientv3.EventTypeDelete {
-					logger.Logger.Info(
+					log.Info(
 						"Lock released, executing release action",
 						zap.String("lockKey", lockKey),
 					)
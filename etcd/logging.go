@@ -0,0 +1,93 @@
+package etcd
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/DeltaNicola/infralib/logger"
+	clientv3 "go.etcd.io/etcd/client/v3"
+	"go.uber.org/zap"
+)
+
+func LogLevelsKey(service string) string {
+	return fmt.Sprintf("/config/%s/logging", service)
+}
+
+// WatchLogLevels applies the logger.LevelConfig stored as JSON at key to the
+// running logger, e.g. {"level": "info", "sinks": {"console": "debug"},
+// "loggers": {"kafka": "warn"}}, and goes back to the default levels when
+// the key is deleted. It blocks until the watch ends.
+//
+// Deprecated: use WatchLogLevelsContext.
+func WatchLogLevels(client *clientv3.Client, key string) {
+	WatchLogLevelsContext(context.Background(), client, key)
+}
+
+// WatchLogLevelsContext is WatchLogLevels stopping when ctx is done.
+func WatchLogLevelsContext(ctx context.Context, client *clientv3.Client, key string) {
+	resp, err := client.Get(ctx, key)
+	if err != nil {
+		logger.Logger.Error(
+			"Error Reading Log Levels",
+			zap.String("key", key),
+			zap.Error(err),
+		)
+		return
+	}
+
+	if len(resp.Kvs) > 0 {
+		applyLogLevels(key, resp.Kvs[0].Value)
+	}
+
+	rch := client.Watch(ctx, key, clientv3.WithRev(resp.Header.Revision+1))
+
+	logger.Logger.Info(
+		"Log Levels Watcher Started",
+		zap.String("key", key),
+	)
+
+	for wresp := range rch {
+		for _, ev := range wresp.Events {
+			switch ev.Type {
+			case clientv3.EventTypePut:
+				applyLogLevels(key, ev.Kv.Value)
+			case clientv3.EventTypeDelete:
+				logger.ResetLevels()
+				logger.Logger.Info(
+					"Log Levels Reset To Defaults",
+					zap.String("key", key),
+				)
+			}
+		}
+	}
+}
+
+func applyLogLevels(key string, value []byte) {
+	var config logger.LevelConfig
+	if err := json.Unmarshal(value, &config); err != nil {
+		logger.Logger.Error(
+			"Error Reading Log Levels",
+			zap.String("key", key),
+			zap.String("value", string(value)),
+			zap.Error(err),
+		)
+		return
+	}
+
+	if err := logger.ApplyLevels(config); err != nil {
+		logger.Logger.Error(
+			"Error Applying Log Levels",
+			zap.String("key", key),
+			zap.String("value", string(value)),
+			zap.Error(err),
+		)
+		return
+	}
+
+	logger.Logger.Info(
+		"Log Levels Updated",
+		zap.String("key", key),
+		zap.Any("levels", logger.Levels()),
+	)
+}
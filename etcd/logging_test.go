@@ -0,0 +1,32 @@
+package etcd
+
+import (
+	"testing"
+
+	"github.com/DeltaNicola/infralib/logger"
+	"github.com/DeltaNicola/infralib/logger/loggertest"
+)
+
+func TestApplyLogLevels(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		message loggertest.Matcher
+	}{
+		{"valid", `{"level":"warn","loggers":{"kafka":"error"}}`, loggertest.Info("Log Levels Updated")},
+		{"invalid json", `{"level":`, loggertest.Error("Error Reading Log Levels")},
+		{"invalid level", `{"loggers":{"kafka":"loud"}}`, loggertest.Error("Error Applying Log Levels")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := loggertest.New(t)
+			t.Cleanup(logger.ResetLevels)
+
+			applyLogLevels("/config/orders/logging", []byte(tt.value))
+
+			logs.AssertLogged(t, tt.message, loggertest.Field("key", "/config/orders/logging"))
+			logs.AssertCount(t, 1)
+		})
+	}
+}
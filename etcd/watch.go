@@ -9,10 +9,19 @@ import (
 	"go.uber.org/zap"
 )
 
+// Deprecated: use WatchKeyChangesContext.
 func WatchKeyChanges(client *clientv3.Client, key string, configChan chan<- interface{}) {
-	rch := client.Watch(context.Background(), key)
+	WatchKeyChangesContext(context.Background(), client, key, configChan)
+}
+
+// WatchKeyChangesContext sends the JSON objects put at key to configChan
+// until ctx is done.
+func WatchKeyChangesContext(ctx context.Context, client *clientv3.Client, key string, configChan chan<- interface{}) {
+	log := logger.FromContext(ctx)
 
-	logger.Logger.Info(
+	rch := client.Watch(ctx, key)
+
+	log.Info(
 		"Watcher started",
 		zap.String("key", key),
 	)
@@ -22,7 +31,7 @@ func WatchKeyChanges(client *clientv3.Client, key string, configChan chan<- inte
 			if ev.Type == clientv3.EventTypePut {
 				value := ev.Kv.Value
 
-				logger.Logger.Info(
+				log.Info(
 					"New Configuration Received",
 					zap.String("key", key),
 					zap.String("value", string(value)),
@@ -30,7 +39,7 @@ func WatchKeyChanges(client *clientv3.Client, key string, configChan chan<- inte
 
 				var jsonData map[string]interface{}
 				if err := json.Unmarshal(value, &jsonData); err != nil {
-					logger.Logger.Error(
+					log.Error(
 						"Error Reading New ETCD Configuration",
 						zap.String("key", key),
 						zap.String("value", string(value)),
@@ -38,8 +47,12 @@ func WatchKeyChanges(client *clientv3.Client, key string, configChan chan<- inte
 					continue
 				}
 
-				configChan <- jsonData
-				logger.Logger.Info(
+				select {
+				case configChan <- jsonData:
+				case <-ctx.Done():
+					return
+				}
+				log.Info(
 					"New ETCD Configuration",
 					zap.String("key", key),
 					zap.String("value", string(value)),
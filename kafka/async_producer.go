@@ -0,0 +1,179 @@
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/DeltaNicola/infralib/logger"
+	"github.com/IBM/sarama"
+	"go.uber.org/zap"
+)
+
+// publishTimeout bounds the time Publish waits for the producer to accept a
+// message.
+const publishTimeout = time.Second
+
+type KafkaAsyncProducer struct {
+	producer sarama.AsyncProducer
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	inFlight int
+	idle     chan struct{} // Closed when inFlight drops to zero
+}
+
+func NewKafkaAsyncProducer(brokers []string, flushFrequency time.Duration, flushMessages int) (*KafkaAsyncProducer, error) {
+	kp, err := newKafkaAsyncProducer(brokers, flushFrequency, flushMessages, nil, func(err *sarama.ProducerError) {
+		logger.Logger.Error(
+			"Error Sending to Topic",
+			zap.String("topic", err.Msg.Topic),
+			zap.Error(err.Err),
+		)
+	})
+	if err != nil {
+		logger.Logger.Error(
+			"Error Instantiating Async Producer",
+			zap.Strings("brokers", brokers),
+			zap.Error(err),
+		)
+		return nil, err
+	}
+
+	logger.Logger.Info(
+		"Async Producer Created Successfully",
+		zap.Strings("brokers", brokers),
+	)
+
+	return kp, nil
+}
+
+// newKafkaAsyncProducer does not log, so that it can back the logger's own
+// kafka sink. onSuccess may be nil.
+func newKafkaAsyncProducer(brokers []string, flushFrequency time.Duration, flushMessages int, onSuccess func(*sarama.ProducerMessage), onError func(*sarama.ProducerError)) (*KafkaAsyncProducer, error) {
+	config := sarama.NewConfig()
+	config.Producer.Return.Successes = true
+	config.Producer.Return.Errors = true
+	config.Producer.Flush.Frequency = flushFrequency
+	config.Producer.Flush.Messages = flushMessages
+
+	producer, err := sarama.NewAsyncProducer(brokers, config)
+	if err != nil {
+		return nil, fmt.Errorf("errore nella creazione del produttore Kafka asincrono: %v", err)
+	}
+
+	return wrapAsyncProducer(producer, onSuccess, onError), nil
+}
+
+// wrapAsyncProducer reads the successes and errors of producer, which must
+// return both.
+func wrapAsyncProducer(producer sarama.AsyncProducer, onSuccess func(*sarama.ProducerMessage), onError func(*sarama.ProducerError)) *KafkaAsyncProducer {
+	kp := &KafkaAsyncProducer{producer: producer}
+
+	kp.wg.Add(2)
+	go func() {
+		defer kp.wg.Done()
+		for message := range producer.Successes() {
+			if onSuccess != nil {
+				onSuccess(message)
+			}
+			kp.done()
+		}
+	}()
+	go func() {
+		defer kp.wg.Done()
+		for err := range producer.Errors() {
+			onError(err)
+			kp.done()
+		}
+	}()
+
+	return kp
+}
+
+// Publish queues a message without waiting for it to be sent. It returns an
+// error when the producer does not accept it within a second.
+func (kp *KafkaAsyncProducer) Publish(topic string, key, value []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+	defer cancel()
+
+	return kp.PublishContext(ctx, topic, key, value)
+}
+
+// PublishContext queues a message without waiting for it to be sent, giving
+// up when ctx is done before the producer accepts it.
+func (kp *KafkaAsyncProducer) PublishContext(ctx context.Context, topic string, key, value []byte) error {
+	kp.add()
+	return kp.input(ctx, topic, key, value)
+}
+
+// input hands a message already counted by add to the producer.
+func (kp *KafkaAsyncProducer) input(ctx context.Context, topic string, key, value []byte) error {
+	message := &sarama.ProducerMessage{
+		Topic: topic,
+		Value: sarama.ByteEncoder(value),
+	}
+	if key != nil {
+		message.Key = sarama.ByteEncoder(key)
+	}
+
+	select {
+	case kp.producer.Input() <- message:
+		return nil
+	case <-ctx.Done():
+		kp.done()
+		return fmt.Errorf("produttore Kafka occupato, messaggio scartato: %w", ctx.Err())
+	}
+}
+
+func (kp *KafkaAsyncProducer) add() {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+
+	if kp.inFlight == 0 {
+		kp.idle = make(chan struct{})
+	}
+	kp.inFlight++
+}
+
+func (kp *KafkaAsyncProducer) done() {
+	kp.mu.Lock()
+	defer kp.mu.Unlock()
+
+	kp.inFlight--
+	if kp.inFlight == 0 {
+		close(kp.idle)
+	}
+}
+
+// Flush waits until every published message has been acknowledged or has
+// failed, or until timeout expires.
+func (kp *KafkaAsyncProducer) Flush(timeout time.Duration) error {
+	kp.mu.Lock()
+	inFlight, idle := kp.inFlight, kp.idle
+	kp.mu.Unlock()
+
+	if inFlight == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-idle:
+		return nil
+	case <-timer.C:
+		kp.mu.Lock()
+		inFlight = kp.inFlight
+		kp.mu.Unlock()
+		return fmt.Errorf("timeout in attesa dell'invio di %d messaggi Kafka", inFlight)
+	}
+}
+
+func (kp *KafkaAsyncProducer) Close() error {
+	err := kp.producer.Close()
+	kp.wg.Wait()
+	return err
+}
@@ -0,0 +1,242 @@
+package kafka
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/DeltaNicola/infralib/logger"
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+)
+
+// stalledProducer accepts as many messages as input can buffer and never
+// acknowledges them.
+type stalledProducer struct {
+	sarama.AsyncProducer
+	input     chan *sarama.ProducerMessage
+	successes chan *sarama.ProducerMessage
+	errors    chan *sarama.ProducerError
+}
+
+func newStalledProducer(buffer int) *stalledProducer {
+	return &stalledProducer{
+		input:     make(chan *sarama.ProducerMessage, buffer),
+		successes: make(chan *sarama.ProducerMessage),
+		errors:    make(chan *sarama.ProducerError),
+	}
+}
+
+func (p *stalledProducer) Input() chan<- *sarama.ProducerMessage     { return p.input }
+func (p *stalledProducer) Successes() <-chan *sarama.ProducerMessage { return p.successes }
+func (p *stalledProducer) Errors() <-chan *sarama.ProducerError      { return p.errors }
+
+func (p *stalledProducer) Close() error {
+	close(p.successes)
+	close(p.errors)
+	return nil
+}
+
+func newMockAsyncProducer(t *testing.T) *mocks.AsyncProducer {
+	config := mocks.NewTestConfig()
+	config.Producer.Return.Successes = true
+	config.Producer.Return.Errors = true
+	return mocks.NewAsyncProducer(t, config)
+}
+
+func TestAsyncProducerCallbacks(t *testing.T) {
+	failure := errors.New("broker down")
+
+	tests := []struct {
+		name      string
+		expect    func(*mocks.AsyncProducer)
+		successes int
+		errors    int
+	}{
+		{"success", func(p *mocks.AsyncProducer) { p.ExpectInputAndSucceed() }, 1, 0},
+		{"failure", func(p *mocks.AsyncProducer) { p.ExpectInputAndFail(failure) }, 0, 1},
+		{
+			"mixed",
+			func(p *mocks.AsyncProducer) {
+				p.ExpectInputAndSucceed().ExpectInputAndFail(failure).ExpectInputAndSucceed()
+			},
+			2,
+			1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := newMockAsyncProducer(t)
+			tt.expect(mock)
+
+			var mu sync.Mutex
+			var successes, errs int
+			kp := wrapAsyncProducer(mock, func(*sarama.ProducerMessage) {
+				mu.Lock()
+				successes++
+				mu.Unlock()
+			}, func(err *sarama.ProducerError) {
+				mu.Lock()
+				errs++
+				mu.Unlock()
+				if !errors.Is(err.Err, failure) || err.Msg.Topic != "orders" {
+					t.Errorf("got error %v for topic %q", err.Err, err.Msg.Topic)
+				}
+			})
+
+			for n := 0; n < tt.successes+tt.errors; n++ {
+				if err := kp.Publish("orders", []byte("key"), []byte("value")); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := kp.Flush(time.Second); err != nil {
+				t.Fatal(err)
+			}
+
+			mu.Lock()
+			if successes != tt.successes || errs != tt.errors {
+				t.Errorf("got %d successes and %d errors, want %d and %d", successes, errs, tt.successes, tt.errors)
+			}
+			mu.Unlock()
+
+			if err := kp.Close(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestAsyncProducerBusy(t *testing.T) {
+	producer := newStalledProducer(1)
+	kp := wrapAsyncProducer(producer, nil, func(*sarama.ProducerError) {})
+	defer kp.Close()
+
+	if err := kp.Flush(time.Millisecond); err != nil {
+		t.Errorf("got error %v flushing an idle producer", err)
+	}
+
+	if err := kp.Publish("orders", nil, []byte("first")); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	err := kp.PublishContext(ctx, "orders", nil, []byte("second"))
+	if !errors.Is(err, context.DeadlineExceeded) || !strings.HasPrefix(err.Error(), "produttore Kafka occupato, messaggio scartato") {
+		t.Errorf("got error %v, want the producer to be busy", err)
+	}
+
+	// The discarded message is no longer waited for
+	if err := kp.Flush(10 * time.Millisecond); err == nil || err.Error() != "timeout in attesa dell'invio di 1 messaggi Kafka" {
+		t.Errorf("got error %v, want a timeout on the first message", err)
+	}
+
+	message := <-producer.input
+	if message.Key != nil || string(message.Value.(sarama.ByteEncoder)) != "first" {
+		t.Errorf("got message %+v", message)
+	}
+}
+
+func TestLogSink(t *testing.T) {
+	mock := newMockAsyncProducer(t)
+	mock.ExpectInputWithMessageCheckerFunctionAndSucceed(func(message *sarama.ProducerMessage) error {
+		key, _ := message.Key.Encode()
+		value, _ := message.Value.Encode()
+		if message.Topic != "logs" || string(key) != "billing" || string(value) != `{"message":"started"}` {
+			t.Errorf("got message on %q with key %q and value %q", message.Topic, key, value)
+		}
+		return nil
+	})
+
+	sink := startLogSink(wrapAsyncProducer(mock, nil, func(*sarama.ProducerError) {}), "logs", []byte("billing"), 10, false)
+
+	line := []byte(`{"message":"started"}` + "\n")
+	if n, err := sink.Write(line); err != nil || n != len(line) {
+		t.Fatalf("got %d, %v", n, err)
+	}
+	if err := sink.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := sink.Write(line); !errors.Is(err, errLogSinkClosed) {
+		t.Errorf("got error %v writing to a closed sink", err)
+	}
+}
+
+func TestLogSinkQueueFull(t *testing.T) {
+	tests := []struct {
+		name        string
+		blockOnFull bool
+	}{
+		{"drop", false},
+		{"block", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The producer never accepts a message: the first one waits in the
+			// sink goroutine, the second one in the queue
+			producer := newStalledProducer(0)
+			sink := startLogSink(wrapAsyncProducer(producer, nil, func(*sarama.ProducerError) {}), "logs", nil, 1, tt.blockOnFull)
+
+			if _, err := sink.Write([]byte("a")); err != nil {
+				t.Fatal(err)
+			}
+			deadline := time.Now().Add(time.Second)
+			for len(sink.queue) > 0 && time.Now().Before(deadline) {
+				time.Sleep(time.Millisecond)
+			}
+			if _, err := sink.Write([]byte("b")); err != nil {
+				t.Fatal(err)
+			}
+
+			written := make(chan error, 1)
+			start := time.Now()
+			go func() {
+				_, err := sink.Write([]byte("c"))
+				written <- err
+			}()
+
+			if !tt.blockOnFull {
+				if err := <-written; !errors.Is(err, logger.ErrQueueFull) {
+					t.Errorf("got error %v, want logger.ErrQueueFull", err)
+				}
+				if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+					t.Errorf("the write waited %s", elapsed)
+				}
+			} else {
+				select {
+				case err := <-written:
+					t.Fatalf("got %v, want the write to wait", err)
+				case <-time.After(20 * time.Millisecond):
+				}
+			}
+
+			sink.cancel()
+			<-sink.stopped
+			if tt.blockOnFull {
+				if err := <-written; !errors.Is(err, errLogSinkClosed) {
+					t.Errorf("got error %v, want the waiting write to fail on close", err)
+				}
+			}
+			if err := sink.producer.Flush(time.Millisecond); err != nil {
+				t.Errorf("got error %v, want no message left in flight", err)
+			}
+			sink.producer.Close()
+		})
+	}
+}
+
+func TestNewLogSinkWithoutBrokers(t *testing.T) {
+	t.Setenv("KAFKA_LOG_BROKERS", "")
+
+	if _, err := newLogSink(); err == nil || err.Error() != "KAFKA_LOG_BROKERS non impostato" {
+		t.Errorf("got error %v, want KAFKA_LOG_BROKERS non impostato", err)
+	}
+}
@@ -37,9 +37,11 @@ func NewKafkaConsumer(brokers []string) (*KafkaConsumer, error) {
 }
 
 func (kc *KafkaConsumer) ReadFromTopic(ctx context.Context, topic string, messageHandler func([]byte)) error {
+	log := logger.FromContext(ctx)
+
 	partitions, err := kc.consumer.Partitions(topic)
 	if err != nil {
-		logger.Logger.Error(
+		log.Error(
 			"Error Consuming Partition",
 			zap.String("topic", topic),
 			zap.Error(err),
@@ -52,7 +54,7 @@ func (kc *KafkaConsumer) ReadFromTopic(ctx context.Context, topic string, messag
 	for _, partition := range partitions {
 		pc, err := kc.consumer.ConsumePartition(topic, partition, sarama.OffsetNewest)
 		if err != nil {
-			logger.Logger.Error(
+			log.Error(
 				"Error Consuming Partition",
 				zap.String("topic", topic),
 				zap.Int32("partition", partition),
@@ -73,7 +75,7 @@ func (kc *KafkaConsumer) ReadFromTopic(ctx context.Context, topic string, messag
 				select {
 				case msg := <-pc.Messages():
 
-					logger.Logger.Info(
+					log.Info(
 						"New Message Received",
 						zap.String("topic", topic),
 						zap.Int32("partition", msg.Partition),
@@ -81,14 +83,14 @@ func (kc *KafkaConsumer) ReadFromTopic(ctx context.Context, topic string, messag
 
 					messageHandler(msg.Value)
 				case err := <-pc.Errors():
-					logger.Logger.Error(
+					log.Error(
 						"Consumer Error",
 						zap.String("topic", topic),
 						zap.Int32("partition", err.Partition),
 						zap.Error(err),
 					)
 				case <-ctx.Done():
-					logger.Logger.Warn(
+					log.Warn(
 						"Interrupting Consumer",
 						zap.String("topic", topic),
 					)
@@ -99,14 +101,14 @@ func (kc *KafkaConsumer) ReadFromTopic(ctx context.Context, topic string, messag
 	}
 
 	<-ctx.Done()
-	logger.Logger.Warn(
+	log.Warn(
 		"Context Canceled: Waiting for Partition Consumers to Stop",
 		zap.String("topic", topic),
 	)
 
 	wg.Wait()
 
-	logger.Logger.Info(
+	log.Info(
 		"Partition Consumer Stopped",
 		zap.String("topic", topic),
 	)
@@ -0,0 +1,172 @@
+package kafka
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/DeltaNicola/infralib/logger"
+	"github.com/IBM/sarama"
+	"go.uber.org/zap/zapcore"
+)
+
+func init() {
+	logger.RegisterSink(logger.SinkKafka, newLogSink)
+}
+
+// logSink queues the entries and hands them to the producer from a single
+// goroutine, so that logging does not wait for the producer while the
+// brokers are unreachable: entries are dropped when the queue is full,
+// unless blockOnFull is set.
+type logSink struct {
+	producer    *KafkaAsyncProducer
+	topic       string
+	key         []byte
+	blockOnFull bool
+
+	queue   chan []byte
+	ctx     context.Context
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+func newLogSink() (zapcore.WriteSyncer, error) {
+	brokers := strings.Split(os.Getenv("KAFKA_LOG_BROKERS"), ",")
+	if len(brokers) == 0 || brokers[0] == "" {
+		return nil, fmt.Errorf("KAFKA_LOG_BROKERS non impostato")
+	}
+
+	topic, exists := os.LookupEnv("KAFKA_LOG_TOPIC")
+	if !exists {
+		topic = "logs"
+	}
+
+	flushFrequency, err := time.ParseDuration(os.Getenv("KAFKA_LOG_FLUSH_INTERVAL"))
+	if err != nil {
+		flushFrequency = 500 * time.Millisecond
+	}
+
+	flushMessages, err := strconv.Atoi(os.Getenv("KAFKA_LOG_BATCH_SIZE"))
+	if err != nil {
+		flushMessages = 100
+	}
+
+	queueSize, err := strconv.Atoi(os.Getenv("KAFKA_LOG_QUEUE_SIZE"))
+	if err != nil || queueSize <= 0 {
+		queueSize = 10000
+	}
+
+	blockOnFull := strings.EqualFold(os.Getenv("KAFKA_LOG_QUEUE_POLICY"), "block")
+
+	producer, err := newKafkaAsyncProducer(brokers, flushFrequency, flushMessages, func(*sarama.ProducerMessage) {
+		logger.ReportSinkSuccess(logger.SinkKafka, 1)
+	}, func(err *sarama.ProducerError) {
+		// Logging through the logger here would feed the error back into this sink
+		logger.ReportSinkError(logger.SinkKafka, 1, err.Err)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var key []byte
+	if service := os.Getenv("SERVICE_NAME"); service != "" {
+		key = []byte(service)
+	}
+
+	return startLogSink(producer, topic, key, queueSize, blockOnFull), nil
+}
+
+func startLogSink(producer *KafkaAsyncProducer, topic string, key []byte, queueSize int, blockOnFull bool) *logSink {
+	ctx, cancel := context.WithCancel(context.Background())
+	sink := &logSink{
+		producer:    producer,
+		topic:       topic,
+		key:         key,
+		blockOnFull: blockOnFull,
+		queue:       make(chan []byte, queueSize),
+		ctx:         ctx,
+		cancel:      cancel,
+		stopped:     make(chan struct{}),
+	}
+	go sink.run()
+
+	return sink
+}
+
+func (s *logSink) run() {
+	defer close(s.stopped)
+
+	for {
+		select {
+		case value := <-s.queue:
+			// Only fails once the sink is closed
+			_ = s.producer.input(s.ctx, s.topic, s.key, value)
+		case <-s.ctx.Done():
+			for {
+				select {
+				case <-s.queue:
+					s.producer.done()
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+var errLogSinkClosed = errors.New("sink di log Kafka chiuso")
+
+func (s *logSink) Write(p []byte) (int, error) {
+	if s.ctx.Err() != nil {
+		return 0, errLogSinkClosed
+	}
+
+	entry := bytes.TrimRight(p, "\n")
+	value := make([]byte, len(entry))
+	copy(value, entry)
+
+	// Counted from now, so that Sync also waits for the queued entries
+	s.producer.add()
+
+	if s.blockOnFull {
+		select {
+		case s.queue <- value:
+			return len(p), nil
+		case <-s.ctx.Done():
+			s.producer.done()
+			return 0, errLogSinkClosed
+		}
+	}
+
+	select {
+	case s.queue <- value:
+		return len(p), nil
+	default:
+		s.producer.done()
+		return 0, logger.ErrQueueFull
+	}
+}
+
+// Async makes the logger count the entries when the producer acknowledges
+// them rather than when they are queued.
+func (s *logSink) Async() bool {
+	return true
+}
+
+func (s *logSink) Sync() error {
+	return s.producer.Flush(5 * time.Second)
+}
+
+// Close is called when the logger is closed. The entries the producer has
+// not accepted within the Sync timeout are dropped.
+func (s *logSink) Close() error {
+	err := s.Sync()
+	s.cancel()
+	<-s.stopped
+	return errors.Join(err, s.producer.Close())
+}
@@ -0,0 +1,72 @@
+package kafka
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/DeltaNicola/infralib/logger/loggertest"
+	"github.com/IBM/sarama"
+	"github.com/IBM/sarama/mocks"
+)
+
+func TestPushToTopic(t *testing.T) {
+	tests := []struct {
+		name    string
+		expect  func(*mocks.SyncProducer)
+		message interface{}
+		err     string
+		logged  loggertest.Matcher
+	}{
+		{
+			name: "sent",
+			expect: func(p *mocks.SyncProducer) {
+				p.ExpectSendMessageWithCheckerFunctionAndSucceed(func(value []byte) error {
+					if string(value) != `{"id":42}` {
+						return errors.New("unexpected value " + string(value))
+					}
+					return nil
+				})
+			},
+			message: map[string]int{"id": 42},
+			logged:  loggertest.Info("Messaged Posted Successfully"),
+		},
+		{
+			name:    "not json",
+			expect:  func(*mocks.SyncProducer) {},
+			message: make(chan int),
+			err:     "errore nella conversione in JSON del messaggio",
+			logged:  loggertest.Error("Error JSON Conversion"),
+		},
+		{
+			name:    "send failed",
+			expect:  func(p *mocks.SyncProducer) { p.ExpectSendMessageAndFail(sarama.ErrNotLeaderForPartition) },
+			message: map[string]int{"id": 42},
+			err:     "errore durante l'invio al topic Kafka",
+			logged:  loggertest.Error("Error Sending to Topic"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			logs := loggertest.New(t)
+			mock := mocks.NewSyncProducer(t, mocks.NewTestConfig())
+			tt.expect(mock)
+
+			kp := &KafkaProducer{producer: mock}
+			err := kp.PushToTopic("orders", tt.message)
+
+			if tt.err == "" && err != nil {
+				t.Errorf("got error %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.HasPrefix(err.Error(), tt.err)) {
+				t.Errorf("got error %v, want %q", err, tt.err)
+			}
+			logs.AssertLogged(t, tt.logged)
+
+			if err := kp.Close(); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
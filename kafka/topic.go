@@ -115,5 +115,10 @@ func DeleteTopic(brokers []string, topic string) error {
 		zap.String("topic", topic),
 		zap.Strings("brokers", brokers),
 	)
+	logger.Audit.Info(
+		"Topic Deleted",
+		zap.String("topic", topic),
+		zap.Strings("brokers", brokers),
+	)
 	return nil
 }
@@ -0,0 +1,409 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const SinkAudit = "audit"
+
+// Audit records security relevant events, like lock acquisitions and
+// configuration changes, apart from Logger. It writes to the audit
+// destinations of the installed logger, see AuditConfig and Install, and
+// discards everything when there are none.
+var Audit = zap.New(installedAuditCore{}, zap.AddCaller())
+
+// ErrAuditChainBroken is returned by VerifyAudit and VerifyAuditChains when
+// an audit entry was modified, removed or inserted.
+var ErrAuditChainBroken = errors.New("audit log chain broken")
+
+const (
+	auditChainKey    = "audit.chain"
+	auditSeqKey      = "audit.seq"
+	auditPrevHashKey = "audit.prev_hash"
+	auditHashKey     = "audit.hash"
+)
+
+// AuditConfig describes the destinations of Audit. Every entry is written as
+// JSON with its sequence number, the hash of the previous entry and its own
+// hash, computed with HMAC-SHA256 when Key is set and SHA-256 otherwise.
+//
+// Every destination has a chain of its own. The chain of File continues from
+// its last entry when it already exists. The OpenSearch chain starts over with
+// every process run and is identified by its audit.chain field; its entries
+// are spooled while OpenSearch is unreachable, in the directory of File when
+// the spool is not enabled, and are never dropped or evicted.
+type AuditConfig struct {
+	File       string // Append only, never rotated
+	OpenSearch *OpenSearchConfig
+	Key        []byte
+}
+
+func auditConfigFromEnv() AuditConfig {
+	config := AuditConfig{
+		File: envString("LOG_AUDIT_FILE_PATH", "./audit.log"),
+		Key:  []byte(os.Getenv("LOG_AUDIT_HMAC_KEY")),
+	}
+	if index := os.Getenv("LOG_AUDIT_OPEN_SEARCH_INDEX"); index != "" {
+		openSearch := openSearchConfigFromEnv()
+		openSearch.Index = index
+		openSearch.Rollover = RolloverNone
+		openSearch.Retention = ""
+		openSearch.Spool = SpoolConfig{
+			Enabled: true,
+			Dir:     envString("LOG_AUDIT_SPOOL_DIR", filepath.Join(filepath.Dir(config.File), "audit-spool")),
+		}
+		config.OpenSearch = &openSearch
+	}
+	return config
+}
+
+// setupAudit sets up the audit destinations of i. When one fails to set up
+// the others are still used.
+func (i *instance) setupAudit(config AuditConfig) error {
+	var writers []*auditWriter
+	var errs []error
+
+	if config.File != "" {
+		last, err := lastAuditEntry(config.File, config.Key)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to resume audit chain from %s: %w", config.File, err))
+		} else {
+			file, err := os.OpenFile(config.File, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0o600)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to open audit file: %w", err))
+			} else {
+				writers = append(writers, &auditWriter{
+					out:    zapcore.Lock(file),
+					closer: file,
+					key:    config.Key,
+					last:   last,
+				})
+			}
+		}
+	}
+
+	if config.OpenSearch != nil {
+		openSearch := *config.OpenSearch
+		openSearch.Format = FormatJSON
+		openSearch.Batch.BlockOnFull = true
+		if !openSearch.Spool.Enabled {
+			openSearch.Spool = SpoolConfig{Enabled: true, Dir: filepath.Join(filepath.Dir(config.File), "audit-spool")}
+		}
+		openSearch.Spool.MaxSize = 0
+
+		writer, err := newOpenSearchWriter(openSearch, i.sinkErrorReporter(SinkAudit))
+		if err == nil {
+			err = writer.SetupIndex()
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to set up audit OpenSearch index: %w", err))
+		}
+		if writer != nil {
+			writers = append(writers, &auditWriter{
+				out:    writer,
+				closer: writer,
+				key:    config.Key,
+				last:   auditLink{chain: newAuditChain()},
+			})
+		}
+	}
+
+	if len(writers) == 0 {
+		return errors.Join(errs...)
+	}
+
+	outs := make([]zapcore.WriteSyncer, len(writers))
+	for n, writer := range writers {
+		outs[n] = writer
+	}
+	i.auditWriters = writers
+
+	// Every entry is recorded as is: sink levels, logger overrides and
+	// redaction do not apply to the audit log.
+	i.audit = zapcore.NewCore(zapcore.NewJSONEncoder(encoderConfig), zapcore.NewMultiWriteSyncer(outs...), zapcore.DebugLevel).
+		With(i.config.Service.fields())
+
+	return errors.Join(errs...)
+}
+
+func newAuditChain() string {
+	id := make([]byte, 8)
+	_, _ = rand.Read(id)
+	return hex.EncodeToString(id)
+}
+
+// installedAuditCore is the core of Audit, it hands the entries to the audit
+// core of the installed logger.
+type installedAuditCore struct {
+	fields []zapcore.Field
+}
+
+func (c installedAuditCore) core() zapcore.Core {
+	core := current().audit
+	if len(c.fields) > 0 {
+		core = core.With(c.fields)
+	}
+	return core
+}
+
+func (c installedAuditCore) Enabled(level zapcore.Level) bool {
+	return current().audit.Enabled(level)
+}
+
+func (c installedAuditCore) With(fields []zapcore.Field) zapcore.Core {
+	return installedAuditCore{fields: append(c.fields[:len(c.fields):len(c.fields)], fields...)}
+}
+
+func (c installedAuditCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return c.core().Check(entry, checked)
+}
+
+func (c installedAuditCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	return c.core().Write(entry, fields)
+}
+
+func (c installedAuditCore) Sync() error {
+	return current().audit.Sync()
+}
+
+type auditLink struct {
+	chain string
+	seq   uint64
+	hash  string
+}
+
+// auditWriter chains every entry written to the previous one. The chain
+// moves forward even when out fails, so that the verifier reports the gap.
+type auditWriter struct {
+	out    zapcore.WriteSyncer
+	closer io.Closer
+	key    []byte
+
+	mu   sync.Mutex
+	last auditLink
+}
+
+func (w *auditWriter) Write(p []byte) (int, error) {
+	entry := bytes.TrimRight(p, "\n")
+	if len(entry) < 2 || entry[len(entry)-1] != '}' {
+		return 0, fmt.Errorf("audit entries must be JSON objects")
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	seq := w.last.seq + 1
+	var line bytes.Buffer
+	line.Write(entry[:len(entry)-1])
+	if w.last.chain != "" {
+		fmt.Fprintf(&line, `,%q:%q`, auditChainKey, w.last.chain)
+	}
+	fmt.Fprintf(&line, `,%q:%d,%q:%q}`, auditSeqKey, seq, auditPrevHashKey, w.last.hash)
+
+	sum := auditHash(w.key, line.Bytes())
+	line.Truncate(line.Len() - 1)
+	fmt.Fprintf(&line, `,%q:%q}`, auditHashKey, sum)
+	line.WriteByte('\n')
+
+	w.last = auditLink{chain: w.last.chain, seq: seq, hash: sum}
+	if _, err := w.out.Write(line.Bytes()); err != nil {
+		return 0, err
+	}
+	return len(p), nil
+}
+
+func (w *auditWriter) Sync() error {
+	return w.out.Sync()
+}
+
+func (w *auditWriter) close() error {
+	return errors.Join(w.Sync(), w.closer.Close())
+}
+
+func auditHash(key, entry []byte) string {
+	var h hash.Hash
+	if len(key) > 0 {
+		h = hmac.New(sha256.New, key)
+	} else {
+		h = sha256.New()
+	}
+	h.Write(entry)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// parseAuditEntry checks the hash of line and returns its chain link and the
+// hash of the entry before it.
+func parseAuditEntry(line, key []byte) (auditLink, string, error) {
+	suffix := []byte(fmt.Sprintf(",%q:", auditHashKey))
+	i := bytes.LastIndex(line, suffix)
+	if i < 0 {
+		return auditLink{}, "", fmt.Errorf("%s missing", auditHashKey)
+	}
+
+	var sum string
+	if err := json.Unmarshal(bytes.TrimSuffix(line[i+len(suffix):], []byte("}")), &sum); err != nil {
+		return auditLink{}, "", fmt.Errorf("invalid %s: %w", auditHashKey, err)
+	}
+
+	entry := append(line[:i:i], '}')
+	if !hmac.Equal([]byte(sum), []byte(auditHash(key, entry))) {
+		return auditLink{}, "", fmt.Errorf("hash mismatch, the entry was modified")
+	}
+
+	var fields struct {
+		Chain    string `json:"audit.chain"`
+		Seq      uint64 `json:"audit.seq"`
+		PrevHash string `json:"audit.prev_hash"`
+	}
+	if err := json.Unmarshal(entry, &fields); err != nil {
+		return auditLink{}, "", fmt.Errorf("invalid entry: %w", err)
+	}
+
+	return auditLink{chain: fields.Chain, seq: fields.Seq, hash: sum}, fields.PrevHash, nil
+}
+
+// lastAuditEntry returns the link of the last entry of the audit file at
+// path, the zero link when it does not exist or is empty.
+func lastAuditEntry(path string, key []byte) (auditLink, error) {
+	file, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return auditLink{}, nil
+	}
+	if err != nil {
+		return auditLink{}, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return auditLink{}, err
+	}
+
+	const tail = 64 * 1024
+	offset := info.Size() - tail
+	if offset < 0 {
+		offset = 0
+	}
+	data := make([]byte, info.Size()-offset)
+	if _, err := file.ReadAt(data, offset); err != nil && err != io.EOF {
+		return auditLink{}, err
+	}
+
+	data = bytes.TrimRight(data, "\n")
+	if len(data) == 0 {
+		return auditLink{}, nil
+	}
+	start := bytes.LastIndexByte(data, '\n') + 1
+	if start == 0 && offset > 0 {
+		return auditLink{}, fmt.Errorf("last entry is longer than %d bytes", tail)
+	}
+
+	link, _, err := parseAuditEntry(data[start:], key)
+	if err != nil {
+		return auditLink{}, fmt.Errorf("%w: last entry: %v", ErrAuditChainBroken, err)
+	}
+	return link, nil
+}
+
+// AuditVerification describes a verified audit log. Entries removed from the
+// end of a chain go unnoticed: keeping LastHash elsewhere detects it on the
+// next verification. LastSeq and LastHash are the ones of the last entry
+// read.
+type AuditVerification struct {
+	Entries  int
+	Chains   int
+	LastSeq  uint64
+	LastHash string
+}
+
+// VerifyAudit reads the audit entries in r, one JSON object per line in the
+// order they were written, and checks that none was modified, removed or
+// inserted, returning an error wrapping ErrAuditChainBroken otherwise. The
+// entries must form a single chain starting from the first one, as in an
+// audit file. key must be the AuditConfig Key the entries were written with:
+// without a key anyone able to edit the log can also recompute the hashes.
+func VerifyAudit(r io.Reader, key []byte) (AuditVerification, error) {
+	return verifyAudit(r, key, false)
+}
+
+// VerifyAuditChains verifies an export of an OpenSearch audit index, which
+// holds a chain for every process run, see VerifyAudit. The entries of each
+// chain must be in sequence order, e.g. sorted by audit.seq, and every chain
+// must start from its first entry. Whole chains removed from
+// the export go unnoticed.
+func VerifyAuditChains(r io.Reader, key []byte) (AuditVerification, error) {
+	return verifyAudit(r, key, true)
+}
+
+func verifyAudit(r io.Reader, key []byte, chains bool) (AuditVerification, error) {
+	var result AuditVerification
+	last := map[string]auditLink{}
+
+	reader := bufio.NewReader(r)
+	for number := 1; ; number++ {
+		line, err := reader.ReadBytes('\n')
+		if err != nil && err != io.EOF {
+			return result, err
+		}
+
+		if line = bytes.TrimSpace(line); len(line) > 0 {
+			link, prevHash, parseErr := parseAuditEntry(line, key)
+			if parseErr != nil {
+				return result, fmt.Errorf("%w at line %d: %v", ErrAuditChainBroken, number, parseErr)
+			}
+
+			chain := ""
+			if chains {
+				chain = link.chain
+			}
+
+			previous, exists := last[chain]
+			switch {
+			case !exists && (link.seq != 1 || prevHash != ""):
+				return result, fmt.Errorf("%w at line %d: the chain starts at entry %d", ErrAuditChainBroken, number, link.seq)
+			case !exists:
+				result.Chains++
+			case link.seq != previous.seq+1:
+				return result, fmt.Errorf("%w at line %d: entry %d follows entry %d", ErrAuditChainBroken, number, link.seq, previous.seq)
+			case prevHash != previous.hash:
+				return result, fmt.Errorf("%w at line %d: previous hash does not match entry %d", ErrAuditChainBroken, number, previous.seq)
+			}
+
+			last[chain] = link
+			result.Entries++
+			result.LastSeq = link.seq
+			result.LastHash = link.hash
+		}
+
+		if err == io.EOF {
+			return result, nil
+		}
+	}
+}
+
+// VerifyAuditFile verifies the audit file at path, see VerifyAudit.
+func VerifyAuditFile(path string, key []byte) (AuditVerification, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return AuditVerification{}, err
+	}
+	defer file.Close()
+
+	return VerifyAudit(file, key)
+}
@@ -0,0 +1,162 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type bufferSyncer struct {
+	bytes.Buffer
+}
+
+func (b *bufferSyncer) Sync() error {
+	return nil
+}
+
+// auditLines writes entries through an audit chain and returns the lines.
+func auditLines(t *testing.T, key []byte, chain string, entries ...string) []string {
+	t.Helper()
+
+	out := &bufferSyncer{}
+	writer := &auditWriter{out: out, key: key, last: auditLink{chain: chain}}
+	for _, entry := range entries {
+		if _, err := writer.Write([]byte(entry + "\n")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+}
+
+func TestVerifyAudit(t *testing.T) {
+	key := []byte("secret")
+	lines := auditLines(t, key, "", `{"message":"a"}`, `{"message":"b"}`, `{"message":"c"}`, `{"message":"d"}`)
+	restarted := auditLines(t, key, "", `{"message":"e"}`)
+
+	tests := []struct {
+		name    string
+		lines   []string
+		key     []byte
+		entries int
+		err     string
+	}{
+		{name: "intact", lines: lines, key: key, entries: 4},
+		{name: "empty", key: key},
+		{name: "blank lines", lines: []string{"", lines[0], "  ", lines[1], ""}, key: key, entries: 2},
+		{
+			name:  "modified",
+			lines: []string{lines[0], strings.Replace(lines[1], `"b"`, `"x"`, 1), lines[2]},
+			key:   key,
+			err:   "line 2: hash mismatch",
+		},
+		{name: "removed", lines: []string{lines[0], lines[2], lines[3]}, key: key, err: "line 2: entry 3 follows entry 1"},
+		{name: "removed first", lines: lines[1:], key: key, err: "line 1: the chain starts at entry 2"},
+		{name: "reordered", lines: []string{lines[0], lines[2], lines[1]}, key: key, err: "line 2: entry 3 follows entry 1"},
+		{
+			name:  "inserted",
+			lines: []string{lines[0], lines[1], auditLines(t, key, "", `{"message":"x"}`, `{"message":"y"}`, `{"message":"z"}`)[2], lines[2]},
+			key:   key,
+			err:   "line 3: previous hash does not match entry 2",
+		},
+		{name: "restarted", lines: append(append([]string{}, lines...), restarted...), key: key, err: "line 5: entry 1 follows entry 4"},
+		{name: "wrong key", lines: lines, key: []byte("other"), err: "line 1: hash mismatch"},
+		{name: "no hash", lines: []string{`{"message":"a"}`}, key: key, err: "audit.hash missing"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := VerifyAudit(strings.NewReader(strings.Join(tt.lines, "\n")), tt.key)
+
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("got error %v", err)
+				}
+				if result.Entries != tt.entries {
+					t.Errorf("got %d entries, want %d", result.Entries, tt.entries)
+				}
+				if tt.entries > 0 && result.Chains != 1 {
+					t.Errorf("got %d chains, want 1", result.Chains)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrAuditChainBroken) || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %v, want ErrAuditChainBroken with %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestVerifyAuditChains(t *testing.T) {
+	key := []byte("secret")
+	first := auditLines(t, key, "run1", `{"message":"a"}`, `{"message":"b"}`)
+	second := auditLines(t, key, "run2", `{"message":"c"}`, `{"message":"d"}`)
+
+	tests := []struct {
+		name   string
+		lines  []string
+		chains int
+		err    string
+	}{
+		{name: "consecutive", lines: append(append([]string{}, first...), second...), chains: 2},
+		{name: "interleaved", lines: []string{first[0], second[0], first[1], second[1]}, chains: 2},
+		{name: "removed", lines: []string{first[0], first[1], second[1]}, err: "line 3: the chain starts at entry 2"},
+		{name: "moved across chains", lines: []string{first[0], second[1]}, err: "line 2: the chain starts at entry 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := VerifyAuditChains(strings.NewReader(strings.Join(tt.lines, "\n")), key)
+
+			if tt.err == "" {
+				if err != nil {
+					t.Fatalf("got error %v", err)
+				}
+				if result.Chains != tt.chains {
+					t.Errorf("got %d chains, want %d", result.Chains, tt.chains)
+				}
+				return
+			}
+
+			if !errors.Is(err, ErrAuditChainBroken) || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("got error %v, want ErrAuditChainBroken with %q", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestAuditFileResume(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	key := []byte("secret")
+
+	for run := 0; run < 2; run++ {
+		i := newInstance(Config{})
+		if err := i.setupAudit(AuditConfig{File: path, Key: key}); err != nil {
+			t.Fatal(err)
+		}
+		if err := i.audit.Write(zapcore.Entry{Message: "Lock Acquired"}, nil); err != nil {
+			t.Fatal(err)
+		}
+		i.close()
+	}
+
+	result, err := VerifyAuditFile(path, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Entries != 2 || result.LastSeq != 2 || result.Chains != 1 {
+		t.Errorf("got %+v, want 2 entries in a single chain", result)
+	}
+
+	if err := os.WriteFile(path, []byte("garbage\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if err := newInstance(Config{}).setupAudit(AuditConfig{File: path, Key: key}); !errors.Is(err, ErrAuditChainBroken) {
+		t.Errorf("got error %v, want ErrAuditChainBroken when the last entry is invalid", err)
+	}
+}
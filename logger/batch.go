@@ -0,0 +1,262 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrQueueFull is returned by the sinks dropping an entry because their
+	// queue is full. Writers of registered sinks return it, or wrap it, to
+	// have the entry counted as dropped rather than failed.
+	ErrQueueFull = errors.New("log queue is full, entry dropped")
+	// errSpooled marks flush errors of entries kept to be sent again later.
+	errSpooled = errors.New("logs spooled to disk")
+)
+
+// partialFlush is returned by flush when not every entry was written, with
+// the number of entries written, kept to be sent again later and failed.
+type partialFlush struct {
+	written int
+	retried int
+	failed  int
+	err     error // nil when the entries were only kept for later
+}
+
+func (p *partialFlush) Error() string {
+	if p.err == nil {
+		return fmt.Sprintf("%d logs kept to be sent later", p.retried)
+	}
+	return p.err.Error()
+}
+
+func (p *partialFlush) Unwrap() error {
+	return p.err
+}
+
+type BatchConfig struct {
+	Size          int
+	QueueSize     int
+	FlushInterval time.Duration
+	BlockOnFull   bool
+}
+
+func defaultBatchConfig() BatchConfig {
+	return BatchConfig{
+		Size:          500,
+		QueueSize:     10000,
+		FlushInterval: 2 * time.Second,
+		BlockOnFull:   false,
+	}
+}
+
+// batchConfigFromEnv reads <prefix>_BATCH_SIZE, <prefix>_QUEUE_SIZE,
+// <prefix>_FLUSH_INTERVAL and <prefix>_QUEUE_POLICY (drop or block).
+func batchConfigFromEnv(prefix string) BatchConfig {
+	config := defaultBatchConfig()
+	config.Size = envInt(prefix+"_BATCH_SIZE", config.Size)
+	config.QueueSize = envInt(prefix+"_QUEUE_SIZE", config.QueueSize)
+	config.FlushInterval = envDuration(prefix+"_FLUSH_INTERVAL", config.FlushInterval)
+	config.BlockOnFull = strings.EqualFold(envString(prefix+"_QUEUE_POLICY", "drop"), "block")
+	return config
+}
+
+type batcher[T any] struct {
+	config   BatchConfig
+	flush    func([]T) error
+	onTick   func() error
+	onError  func(error)
+	stats    *sinkStats
+	queue    chan T
+	flushReq chan chan error
+	stop     chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+func newBatcher[T any](sink string, config BatchConfig, flush func([]T) error) *batcher[T] {
+	defaults := defaultBatchConfig()
+	if config.Size <= 0 {
+		config.Size = defaults.Size
+	}
+	if config.QueueSize <= 0 {
+		config.QueueSize = defaults.QueueSize
+	}
+	if config.FlushInterval <= 0 {
+		config.FlushInterval = defaults.FlushInterval
+	}
+
+	b := &batcher[T]{
+		config: config,
+		flush:  flush,
+		onError: func(err error) {
+			reportSinkError(sink, err)
+		},
+		stats:    statsFor(sink),
+		queue:    make(chan T, config.QueueSize),
+		flushReq: make(chan chan error),
+		stop:     make(chan struct{}),
+		stopped:  make(chan struct{}),
+	}
+
+	b.stats.async.Store(true)
+
+	return b
+}
+
+func (b *batcher[T]) start() *batcher[T] {
+	go b.run()
+	return b
+}
+
+func (b *batcher[T]) add(item T) error {
+	if b.config.BlockOnFull {
+		select {
+		case b.queue <- item:
+			return nil
+		case <-b.stopped:
+			return errors.New("log queue is closed")
+		}
+	}
+
+	select {
+	case b.queue <- item:
+		return nil
+	default:
+		return ErrQueueFull
+	}
+}
+
+func (b *batcher[T]) sync() error {
+	done := make(chan error, 1)
+	select {
+	case b.flushReq <- done:
+		return <-done
+	case <-b.stopped:
+		return nil
+	}
+}
+
+func (b *batcher[T]) close() error {
+	b.once.Do(func() {
+		close(b.stop)
+	})
+	<-b.stopped
+	return nil
+}
+
+func (b *batcher[T]) run() {
+	defer close(b.stopped)
+
+	ticker := time.NewTicker(b.config.FlushInterval)
+	defer ticker.Stop()
+
+	batch := make([]T, 0, b.config.Size)
+	send := func() error {
+		var errs []error
+		for len(batch) > 0 {
+			n := min(len(batch), b.config.Size)
+			var partial *partialFlush
+			switch err := b.flush(batch[:n]); {
+			case err == nil:
+				b.stats.success(n)
+			case errors.As(err, &partial):
+				if partial.written > 0 {
+					b.stats.success(partial.written)
+				}
+				b.stats.retried.Add(uint64(partial.retried))
+				if partial.failed > 0 {
+					b.stats.failure(partial.failed, err)
+				}
+				if partial.err != nil {
+					errs = append(errs, partial.err)
+				}
+			default:
+				b.stats.failure(n, err)
+				errs = append(errs, err)
+			}
+			batch = batch[n:]
+		}
+		batch = make([]T, 0, b.config.Size)
+		return errors.Join(errs...)
+	}
+	drain := func() {
+		for {
+			select {
+			case item := <-b.queue:
+				batch = append(batch, item)
+			default:
+				return
+			}
+		}
+	}
+
+	for {
+		select {
+		case item := <-b.queue:
+			batch = append(batch, item)
+			if len(batch) >= b.config.Size {
+				if err := send(); err != nil {
+					b.onError(err)
+				}
+			}
+		case <-ticker.C:
+			if b.onTick != nil {
+				if err := b.onTick(); err != nil {
+					b.onError(err)
+				}
+			}
+			if err := send(); err != nil {
+				b.onError(err)
+			}
+		case done := <-b.flushReq:
+			drain()
+			done <- send()
+		case <-b.stop:
+			drain()
+			if err := send(); err != nil {
+				b.onError(err)
+			}
+			return
+		}
+	}
+}
+
+// retry calls send until it succeeds, fails with a permanent error or
+// maxRetries is reached, waiting the delay send returns or an exponential
+// backoff between attempts. It runs in the batcher goroutine, so it gives up
+// early rather than wait more than maxWait in total, zero meaning no limit.
+// onRetry is called before every new attempt.
+func retry(maxRetries int, maxWait time.Duration, permanent error, send func() (time.Duration, error), onRetry func()) error {
+	var waited time.Duration
+	for attempt := 0; ; attempt++ {
+		delay, err := send()
+		if err == nil || errors.Is(err, permanent) || attempt >= maxRetries {
+			return err
+		}
+
+		if delay == 0 {
+			delay = min(500*time.Millisecond<<attempt, 30*time.Second)
+		}
+		if maxWait > 0 && waited+delay > maxWait {
+			return err
+		}
+		waited += delay
+
+		onRetry()
+		time.Sleep(delay)
+	}
+}
+
+func retryAfter(resp *http.Response) time.Duration {
+	seconds, err := strconv.Atoi(resp.Header.Get("Retry-After"))
+	if err != nil {
+		return 0
+	}
+	return time.Duration(seconds) * time.Second
+}
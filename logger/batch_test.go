@@ -0,0 +1,125 @@
+package logger
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// flushRecorder records the batches flushed by a batcher.
+type flushRecorder struct {
+	mu      sync.Mutex
+	batches []string
+	err     error
+}
+
+func (r *flushRecorder) flush(items []string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.batches = append(r.batches, strings.Join(items, ","))
+	return r.err
+}
+
+func (r *flushRecorder) flushed() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]string{}, r.batches...)
+}
+
+func TestBatcherFlush(t *testing.T) {
+	tests := []struct {
+		name  string
+		size  int
+		items []string
+		// flush is how the pending items are sent at the end
+		flush func(*batcher[string]) error
+		want  []string
+	}{
+		{"sync", 10, []string{"a", "b", "c"}, (*batcher[string]).sync, []string{"a,b,c"}},
+		{"close", 10, []string{"a", "b"}, (*batcher[string]).close, []string{"a,b"}},
+		{"full batches", 2, []string{"a", "b", "c", "d", "e"}, (*batcher[string]).sync, []string{"a,b", "c,d", "e"}},
+		{"nothing pending", 10, nil, (*batcher[string]).sync, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := &flushRecorder{}
+			b := newBatcher("test", BatchConfig{Size: tt.size, FlushInterval: time.Hour}, recorder.flush).start()
+			defer b.close()
+
+			for _, item := range tt.items {
+				if err := b.add(item); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := tt.flush(b); err != nil {
+				t.Fatal(err)
+			}
+
+			if got := recorder.flushed(); strings.Join(got, "|") != strings.Join(tt.want, "|") {
+				t.Errorf("got batches %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBatcherFlushInterval(t *testing.T) {
+	recorder := &flushRecorder{}
+	b := newBatcher("test", BatchConfig{Size: 10, FlushInterval: 10 * time.Millisecond}, recorder.flush).start()
+	defer b.close()
+
+	if err := b.add("a"); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for len(recorder.flushed()) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("the batch was not flushed after the flush interval")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestBatcherErrors(t *testing.T) {
+	recorder := &flushRecorder{err: errors.New("unavailable")}
+	reported := make(chan error, 1)
+	b := newBatcher("test", BatchConfig{Size: 1, FlushInterval: time.Hour}, recorder.flush)
+	b.onError = func(err error) { reported <- err }
+	b.start()
+	defer b.close()
+
+	if err := b.add("a"); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case err := <-reported:
+		if !errors.Is(err, recorder.err) {
+			t.Errorf("got error %v, want %v", err, recorder.err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("the flush error was not reported")
+	}
+
+	if err := b.add("b"); err != nil {
+		t.Fatal(err)
+	}
+	<-reported
+	if err := b.sync(); err != nil {
+		t.Errorf("got error %v syncing an empty batch", err)
+	}
+}
+
+func TestBatcherQueueFull(t *testing.T) {
+	// Not started, nothing reads the queue
+	b := newBatcher("test", BatchConfig{QueueSize: 1}, func([]string) error { return nil })
+
+	if err := b.add("a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.add("b"); !errors.Is(err, ErrQueueFull) {
+		t.Errorf("got error %v, want ErrQueueFull", err)
+	}
+}
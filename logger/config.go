@@ -0,0 +1,400 @@
+package logger
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Config describes a logger built by New. A nil sink config disables the
+// sink.
+type Config struct {
+	// Development turns on zap development mode and makes the console sink
+	// default to the console format.
+	Development bool
+
+	// Level is the level of the sinks without one in Levels, except the ones
+	// with a default of their own, like discord.
+	Level  zapcore.Level
+	Levels map[string]zapcore.Level
+
+	// Format is the format of the sinks without one in Formats, JSON when
+	// empty.
+	Format  string
+	Formats map[string]string
+
+	Redact RedactConfig
+	// Service is added to every entry.
+	Service ServiceInfo
+
+	Console    bool
+	File       *FileConfig
+	OpenSearch *OpenSearchConfig
+	Loki       *LokiConfig
+	OTLP       *OTLPConfig
+	Syslog     *SyslogConfig
+	Memory     *MemoryConfig
+	Discord    *DiscordConfig
+	// Audit sets up the audit destinations used by Audit once the logger is
+	// installed, nil disables them.
+	Audit *AuditConfig
+	// Digest reports the errors grouped by fingerprint, nil disables it.
+	Digest *DigestConfig
+	// Sinks enables sinks added with RegisterSink, e.g. SinkKafka.
+	Sinks []string
+
+	// ErrorOutput receives the errors of zap and of the sinks, a rate
+	// limited stderr when nil.
+	ErrorOutput zapcore.WriteSyncer
+
+	ZapOptions []zap.Option
+}
+
+type MemoryConfig struct {
+	Size int // Number of entries kept
+}
+
+// DefaultConfig logs JSON to the console from the info level, with the
+// default redaction.
+func DefaultConfig() Config {
+	return Config{
+		Level:   zapcore.InfoLevel,
+		Redact:  RedactConfig{Enabled: true, Fields: defaultRedactedFields},
+		Console: true,
+	}
+}
+
+// ConfigFromEnv reads the Config described by the env variables in the
+// README. Invalid values are reported in the error, the returned Config then
+// uses the defaults for them.
+func ConfigFromEnv() (Config, error) {
+	config := DefaultConfig()
+	var errs []error
+
+	sinks := append([]string{
+		SinkConsole, SinkFile, SinkOpenSearch, SinkDiscord, SinkSyslog,
+		SinkLoki, SinkOTLP, SinkMemory, SinkKafka,
+	}, registeredSinks()...)
+
+	if value, exists := os.LookupEnv("LOG_LEVEL"); exists {
+		level, err := zapcore.ParseLevel(value)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("invalid LOG_LEVEL: %w", err))
+		} else {
+			config.Level = level
+		}
+	}
+	config.Format = strings.ToLower(os.Getenv("LOG_FORMAT"))
+
+	config.Levels = map[string]zapcore.Level{}
+	config.Formats = map[string]string{}
+	for _, sink := range sinks {
+		name := strings.ToUpper(sink)
+		if value, exists := os.LookupEnv("LOG_LEVEL_" + name); exists {
+			level, err := zapcore.ParseLevel(value)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("invalid LOG_LEVEL_%s: %w", name, err))
+			} else {
+				config.Levels[sink] = level
+			}
+		}
+		if value := os.Getenv("LOG_FORMAT_" + name); value != "" {
+			config.Formats[sink] = strings.ToLower(value)
+		}
+	}
+
+	redact, err := redactConfigFromEnv()
+	if err != nil {
+		errs = append(errs, err)
+	}
+	config.Redact = redact
+
+	if envBool("LOG_SERVICE_FIELDS", true) {
+		config.Service = serviceInfoFromEnv()
+	}
+
+	if _, exists := os.LookupEnv("LOG_ERROR_OUTPUT_RATE_LIMIT"); exists {
+		config.ErrorOutput = RateLimitedErrorOutput(zapcore.Lock(os.Stderr), envInt("LOG_ERROR_OUTPUT_RATE_LIMIT", 10), time.Minute)
+	}
+
+	config.Console = envBool("LOG_ON_CONSOLE", true)
+	if envBool("LOG_ON_FILE", false) {
+		file := fileConfigFromEnv()
+		config.File = &file
+	}
+	if envBool("LOG_ON_OPEN_SEARCH", false) {
+		openSearch := openSearchConfigFromEnv()
+		config.OpenSearch = &openSearch
+	}
+	if envBool("LOG_ON_LOKI", false) {
+		loki := lokiConfigFromEnv()
+		config.Loki = &loki
+	}
+	if envBool("LOG_ON_OTLP", false) {
+		otlp := otlpConfigFromEnv()
+		config.OTLP = &otlp
+	}
+	if envBool("LOG_ON_SYSLOG", false) {
+		syslog := syslogConfigFromEnv()
+		config.Syslog = &syslog
+	}
+	if envBool("LOG_ON_MEMORY", false) {
+		config.Memory = &MemoryConfig{Size: envInt("LOG_MEMORY_SIZE", 1000)}
+	}
+	if envBool("LOG_ON_DISCORD", false) {
+		discord := discordConfigFromEnv()
+		config.Discord = &discord
+	}
+	if envBool("LOG_AUDIT", false) {
+		audit := auditConfigFromEnv()
+		config.Audit = &audit
+	}
+	if envBool("LOG_ERROR_DIGEST", false) {
+		digest := digestConfigFromEnv()
+		config.Digest = &digest
+	}
+	for _, sink := range append([]string{SinkKafka}, registeredSinks()...) {
+		if envBool("LOG_ON_"+strings.ToUpper(sink), false) && !contains(config.Sinks, sink) {
+			config.Sinks = append(config.Sinks, sink)
+		}
+	}
+
+	return config, errors.Join(errs...)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+type Option func(*Config)
+
+// WithConfig replaces the whole config, options after it change it further.
+func WithConfig(config Config) Option {
+	return func(c *Config) {
+		*c = config
+	}
+}
+
+func WithDevelopment() Option {
+	return func(c *Config) {
+		c.Development = true
+	}
+}
+
+func WithLevel(level zapcore.Level) Option {
+	return func(c *Config) {
+		c.Level = level
+	}
+}
+
+func WithSinkLevel(sink string, level zapcore.Level) Option {
+	return func(c *Config) {
+		c.Levels = withEntry(c.Levels, sink, level)
+	}
+}
+
+func WithFormat(format string) Option {
+	return func(c *Config) {
+		c.Format = format
+	}
+}
+
+func WithSinkFormat(sink, format string) Option {
+	return func(c *Config) {
+		c.Formats = withEntry(c.Formats, sink, format)
+	}
+}
+
+func withEntry[V any](m map[string]V, key string, value V) map[string]V {
+	result := make(map[string]V, len(m)+1)
+	for k, v := range m {
+		result[k] = v
+	}
+	result[key] = value
+	return result
+}
+
+func WithRedaction(config RedactConfig) Option {
+	return func(c *Config) {
+		c.Redact = config
+	}
+}
+
+func WithService(info ServiceInfo) Option {
+	return func(c *Config) {
+		c.Service = info
+	}
+}
+
+func WithConsole(enabled bool) Option {
+	return func(c *Config) {
+		c.Console = enabled
+	}
+}
+
+func WithFile(config FileConfig) Option {
+	return func(c *Config) {
+		c.File = &config
+	}
+}
+
+func WithOpenSearch(config OpenSearchConfig) Option {
+	return func(c *Config) {
+		c.OpenSearch = &config
+	}
+}
+
+func WithLoki(config LokiConfig) Option {
+	return func(c *Config) {
+		c.Loki = &config
+	}
+}
+
+func WithOTLP(config OTLPConfig) Option {
+	return func(c *Config) {
+		c.OTLP = &config
+	}
+}
+
+func WithSyslog(config SyslogConfig) Option {
+	return func(c *Config) {
+		c.Syslog = &config
+	}
+}
+
+func WithMemory(size int) Option {
+	return func(c *Config) {
+		c.Memory = &MemoryConfig{Size: size}
+	}
+}
+
+func WithDiscord(config DiscordConfig) Option {
+	return func(c *Config) {
+		c.Discord = &config
+	}
+}
+
+func WithAudit(config AuditConfig) Option {
+	return func(c *Config) {
+		c.Audit = &config
+	}
+}
+
+func WithErrorDigest(config DigestConfig) Option {
+	return func(c *Config) {
+		c.Digest = &config
+	}
+}
+
+// WithSinks enables sinks added with RegisterSink.
+func WithSinks(names ...string) Option {
+	return func(c *Config) {
+		c.Sinks = append(c.Sinks[:len(c.Sinks):len(c.Sinks)], names...)
+	}
+}
+
+func WithZapOptions(options ...zap.Option) Option {
+	return func(c *Config) {
+		c.ZapOptions = append(c.ZapOptions[:len(c.ZapOptions):len(c.ZapOptions)], options...)
+	}
+}
+
+// New builds a logger from DefaultConfig changed by opts. When some sinks
+// fail to set up the logger is still returned, writing to the other sinks,
+// together with the errors.
+//
+// The sink levels, redaction, memory sink, error digest and audit
+// destinations of the logger belong to it and do not change any other
+// logger. SetLevel, ApplyLevels, MemoryHandler, ErrorGroups and Audit only
+// act on them once the logger is installed with Install, as InitLogger does.
+func New(opts ...Option) (*zap.Logger, error) {
+	config := DefaultConfig()
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	i := newInstance(config)
+
+	var cores []zapcore.Core
+	var errs []error
+	add := func(core zapcore.Core, err error) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if core != nil {
+			cores = append(cores, core)
+		}
+	}
+
+	if config.Console {
+		add(i.consoleLoggerCore())
+	}
+	if config.File != nil {
+		add(i.fileLoggerCore(*config.File))
+	}
+	if config.OpenSearch != nil {
+		add(i.openSearchLoggercore(*config.OpenSearch))
+	}
+	if config.Loki != nil {
+		add(i.lokiLoggerCore(*config.Loki))
+	}
+	if config.OTLP != nil {
+		add(i.otlpLoggerCore(*config.OTLP))
+	}
+	if config.Syslog != nil {
+		add(i.syslogLoggerCore(*config.Syslog))
+	}
+	if config.Memory != nil {
+		add(i.memoryLoggerCore(config.Memory.Size), nil)
+	}
+	if config.Discord != nil {
+		add(i.discordLoggerCore(*config.Discord))
+	}
+
+	if config.Digest != nil {
+		// First, so that the digest reported when the logger is synced is
+		// written before the sinks are synced.
+		i.digest = newErrorDigest(*config.Digest, i.redactor, i.sinkErrorReporter(SinkDiscord))
+		cores = append([]zapcore.Core{&digestCore{digest: i.digest}}, cores...)
+	}
+
+	registeredCores, registeredErrs := i.registeredSinkCores(config.Sinks)
+	cores = append(cores, registeredCores...)
+	errs = append(errs, registeredErrs...)
+
+	if config.Audit != nil {
+		if err := i.setupAudit(*config.Audit); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	options := []zap.Option{
+		zap.AddCaller(),
+		zap.AddStacktrace(zap.ErrorLevel),
+		zap.ErrorOutput(i.errorOutput),
+	}
+	if config.Development {
+		options = append(options, zap.Development())
+	}
+	if fields := config.Service.fields(); len(fields) > 0 {
+		options = append(options, zap.Fields(fields...))
+	}
+	options = append(options, config.ZapOptions...)
+
+	logger := zap.New(&instanceCore{Core: zapcore.NewTee(cores...), instance: i}, options...)
+	if i.digest != nil {
+		i.digest.start(logger)
+	}
+
+	return logger, errors.Join(errs...)
+}
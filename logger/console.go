@@ -3,14 +3,15 @@ package logger
 import (
 	"os"
 
-	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-func consoleLoggerCore() zapcore.Core {
-	return zapcore.NewCore(
-		zapcore.NewJSONEncoder(encoderConfig),
+func (i *instance) consoleLoggerCore() (zapcore.Core, error) {
+	encoder, err := i.sinkEncoder(SinkConsole)
+
+	return i.newSinkCore(
+		SinkConsole,
+		encoder,
 		zapcore.AddSync(os.Stdout),
-		zap.InfoLevel,
-	)
+	), err
 }
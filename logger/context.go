@@ -0,0 +1,84 @@
+package logger
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel/trace"
+	"go.uber.org/zap"
+)
+
+type contextKey int
+
+const (
+	loggerKey contextKey = iota
+	fieldsKey
+	requestIDKey
+	tenantIDKey
+)
+
+func WithLogger(ctx context.Context, logger *zap.Logger) context.Context {
+	return context.WithValue(ctx, loggerKey, logger)
+}
+
+// WithFields returns a context whose logger includes fields, on top of the
+// ones already attached to ctx.
+func WithFields(ctx context.Context, fields ...zap.Field) context.Context {
+	current, _ := ctx.Value(fieldsKey).([]zap.Field)
+	merged := make([]zap.Field, 0, len(current)+len(fields))
+	merged = append(merged, current...)
+	merged = append(merged, fields...)
+	return context.WithValue(ctx, fieldsKey, merged)
+}
+
+func WithRequestID(ctx context.Context, requestID string) context.Context {
+	return context.WithValue(ctx, requestIDKey, requestID)
+}
+
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey).(string)
+	return requestID
+}
+
+func WithTenantID(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantIDKey, tenantID)
+}
+
+func TenantID(ctx context.Context) string {
+	tenantID, _ := ctx.Value(tenantIDKey).(string)
+	return tenantID
+}
+
+// FromContext returns the logger attached to ctx, or the global Logger, with
+// the fields attached to ctx plus request_id, tenant_id, trace_id and span_id
+// when they are present.
+func FromContext(ctx context.Context) *zap.Logger {
+	if ctx == nil {
+		return Logger
+	}
+
+	logger, ok := ctx.Value(loggerKey).(*zap.Logger)
+	if !ok || logger == nil {
+		logger = Logger
+	}
+
+	fields, _ := ctx.Value(fieldsKey).([]zap.Field)
+	fields = fields[:len(fields):len(fields)]
+
+	if requestID := RequestID(ctx); requestID != "" {
+		fields = append(fields, zap.String("request_id", requestID))
+	}
+	if tenantID := TenantID(ctx); tenantID != "" {
+		fields = append(fields, zap.String("tenant_id", tenantID))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		fields = append(fields,
+			zap.String("trace_id", spanContext.TraceID().String()),
+			zap.String("span_id", spanContext.SpanID().String()),
+		)
+	}
+
+	if len(fields) == 0 {
+		return logger
+	}
+	return logger.With(fields...)
+}
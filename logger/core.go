@@ -0,0 +1,96 @@
+package logger
+
+import (
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// sinkCore wraps the core of every sink. It filters entries with the level
+// of the sink, unless a logger level override applies to the entry's logger
+// name or the entry is an error digest, and redacts fields before they reach
+// the sink. Sinks with their own default level, like discord, only let
+// overrides make them quieter and never get the digest. It also counts the
+// entries written by synchronous sinks and the failures of every sink.
+type sinkCore struct {
+	zapcore.Core
+	instance  *instance
+	level     zap.AtomicLevel
+	quietOnly bool
+	stats     *sinkStats
+}
+
+func (i *instance) newSinkCore(sink string, encoder zapcore.Encoder, out zapcore.WriteSyncer) zapcore.Core {
+	return i.wrapSinkCore(sink, zapcore.NewCore(encoder, out, zapcore.DebugLevel))
+}
+
+// wrapSinkCore expects core to accept every level, filtering is left to
+// the sink level.
+func (i *instance) wrapSinkCore(sink string, core zapcore.Core) zapcore.Core {
+	return &sinkCore{
+		Core:      core,
+		instance:  i,
+		level:     i.sinkLevel(sink),
+		quietOnly: hasDefaultLevel(sink),
+		stats:     statsFor(sink),
+	}
+}
+
+func (c *sinkCore) Enabled(level zapcore.Level) bool {
+	if c.level.Enabled(level) {
+		return true
+	}
+	if c.quietOnly {
+		return false
+	}
+	min, exists := c.instance.minLoggerLevel()
+	return exists && min.Enabled(level)
+}
+
+func (c *sinkCore) Level() zapcore.Level {
+	level := c.level.Level()
+	if c.quietOnly {
+		return level
+	}
+	if min, exists := c.instance.minLoggerLevel(); exists && min < level {
+		return min
+	}
+	return level
+}
+
+func (c *sinkCore) With(fields []zapcore.Field) zapcore.Core {
+	return &sinkCore{
+		Core:      c.Core.With(c.instance.redactor.redactFields(fields)),
+		instance:  c.instance,
+		level:     c.level,
+		quietOnly: c.quietOnly,
+		stats:     c.stats,
+	}
+}
+
+func (c *sinkCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if entry.LoggerName == digestLogger && !c.quietOnly {
+		return checked.AddCore(entry, c)
+	}
+
+	enabled := c.level.Enabled(entry.Level)
+	if override, exists := c.instance.loggerLevel(entry.LoggerName); exists {
+		enabled = override.Enabled(entry.Level) && (enabled || !c.quietOnly)
+	}
+
+	if enabled {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *sinkCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	redactor := c.instance.redactor
+	entry.Message = redactor.redactString(entry.Message)
+	err := c.Core.Write(entry, redactor.redactFields(fields))
+	if err != nil {
+		c.stats.failure(1, err)
+	} else if !c.stats.async.Load() {
+		c.stats.success(1)
+	}
+	return err
+}
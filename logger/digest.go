@@ -0,0 +1,363 @@
+package logger
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/DeltaNicola/infralib/webhook"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// DigestConfig groups the entries at the error level and above by
+// fingerprint and reports the groups seen in every interval, through the
+// logger or as a Discord embed when DiscordWebhook is set.
+type DigestConfig struct {
+	Interval       time.Duration
+	Frames         int // Stack frames in the fingerprint
+	MaxGroups      int // Groups kept per interval, errors of new groups past it are only counted
+	DiscordWebhook string
+}
+
+func digestConfigFromEnv() DigestConfig {
+	config := DigestConfig{
+		Interval:  envDuration("LOG_ERROR_DIGEST_INTERVAL", time.Hour),
+		Frames:    envInt("LOG_ERROR_DIGEST_FRAMES", 3),
+		MaxGroups: envInt("LOG_ERROR_DIGEST_MAX_GROUPS", 100),
+	}
+	if envBool("LOG_ERROR_DIGEST_DISCORD", false) {
+		config.DiscordWebhook = os.Getenv("DISCORD_WEBHOOK_URL")
+	}
+	return config
+}
+
+// ErrorGroup is a set of errors with the same fingerprint: message, caller
+// and functions of the top stack frames.
+type ErrorGroup struct {
+	Fingerprint string    `json:"fingerprint"`
+	Level       string    `json:"level"`
+	Logger      string    `json:"logger,omitempty"`
+	Caller      string    `json:"caller,omitempty"`
+	Message     string    `json:"message"`
+	Frames      []string  `json:"frames,omitempty"`
+	Count       int       `json:"count"`
+	FirstSeen   time.Time `json:"first_seen"`
+	LastSeen    time.Time `json:"last_seen"`
+}
+
+func (g ErrorGroup) MarshalLogObject(encoder zapcore.ObjectEncoder) error {
+	encoder.AddString("fingerprint", g.Fingerprint)
+	encoder.AddString("level", g.Level)
+	if g.Logger != "" {
+		encoder.AddString("logger", g.Logger)
+	}
+	if g.Caller != "" {
+		encoder.AddString("caller", g.Caller)
+	}
+	encoder.AddString("message", g.Message)
+	if len(g.Frames) > 0 {
+		_ = encoder.AddArray("frames", zapcore.ArrayMarshalerFunc(func(frames zapcore.ArrayEncoder) error {
+			for _, frame := range g.Frames {
+				frames.AppendString(frame)
+			}
+			return nil
+		}))
+	}
+	encoder.AddInt("count", g.Count)
+	encoder.AddTime("first_seen", g.FirstSeen)
+	encoder.AddTime("last_seen", g.LastSeen)
+	return nil
+}
+
+type errorGroups []ErrorGroup
+
+func (groups errorGroups) MarshalLogArray(encoder zapcore.ArrayEncoder) error {
+	for _, group := range groups {
+		if err := encoder.AppendObject(group); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// digestLogger names the Error Digest entries, which every sink writes
+// whatever its level and the logger level overrides.
+const digestLogger = "error_digest"
+
+type errorDigest struct {
+	config   DigestConfig
+	redactor *redactor
+	onError  func(error)
+	core     zapcore.Core
+	done     chan struct{}
+	once     sync.Once
+
+	mu       sync.Mutex
+	groups   map[string]*ErrorGroup
+	overflow int
+	since    time.Time
+}
+
+func newErrorDigest(config DigestConfig, redactor *redactor, onError func(error)) *errorDigest {
+	if config.Interval <= 0 {
+		config.Interval = time.Hour
+	}
+	if config.Frames <= 0 {
+		config.Frames = 3
+	}
+	if config.MaxGroups <= 0 {
+		config.MaxGroups = 100
+	}
+
+	return &errorDigest{
+		config:   config,
+		redactor: redactor,
+		onError:  onError,
+		done:     make(chan struct{}),
+		groups:   map[string]*ErrorGroup{},
+		since:    time.Now(),
+	}
+}
+
+// start reports the digests through the core of logger.
+func (d *errorDigest) start(logger *zap.Logger) {
+	d.core = logger.Core()
+	go d.run()
+}
+
+// stop reports the groups of the current interval and stops the digest.
+func (d *errorDigest) stop() {
+	d.once.Do(func() {
+		close(d.done)
+		d.report()
+	})
+}
+
+func (d *errorDigest) run() {
+	ticker := time.NewTicker(d.config.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			d.report()
+		case <-d.done:
+			return
+		}
+	}
+}
+
+func (d *errorDigest) add(entry zapcore.Entry) {
+	message := d.redactor.redactString(entry.Message)
+	frames := stackFunctions(entry.Stack, d.config.Frames)
+	var caller string
+	if entry.Caller.Defined {
+		caller = entry.Caller.TrimmedPath()
+	}
+
+	fingerprint := errorFingerprint(message, caller, frames)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	group, exists := d.groups[fingerprint]
+	if !exists {
+		if len(d.groups) >= d.config.MaxGroups {
+			d.overflow++
+			return
+		}
+		group = &ErrorGroup{
+			Fingerprint: fingerprint,
+			Level:       entry.Level.String(),
+			Logger:      entry.LoggerName,
+			Caller:      caller,
+			Message:     message,
+			Frames:      frames,
+			FirstSeen:   entry.Time,
+		}
+		d.groups[fingerprint] = group
+	}
+	group.Count++
+	group.LastSeen = entry.Time
+}
+
+// take returns the groups of the current interval, most frequent first, and
+// starts a new one.
+func (d *errorDigest) take() (groups []ErrorGroup, overflow int, since time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	groups = d.snapshot()
+	overflow, since = d.overflow, d.since
+	d.groups = map[string]*ErrorGroup{}
+	d.overflow = 0
+	d.since = time.Now()
+	return groups, overflow, since
+}
+
+// snapshot must be called with mu held.
+func (d *errorDigest) snapshot() []ErrorGroup {
+	groups := make([]ErrorGroup, 0, len(d.groups))
+	for _, group := range d.groups {
+		groups = append(groups, *group)
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Count != groups[j].Count {
+			return groups[i].Count > groups[j].Count
+		}
+		return groups[i].FirstSeen.Before(groups[j].FirstSeen)
+	})
+	return groups
+}
+
+func (d *errorDigest) report() {
+	groups, overflow, since := d.take()
+	if len(groups) == 0 && overflow == 0 {
+		return
+	}
+
+	total := overflow
+	for _, group := range groups {
+		total += group.Count
+	}
+	period := time.Since(since).Round(time.Second)
+
+	if d.config.DiscordWebhook == "" {
+		// Checked on the core, the logger would drop the entry below the
+		// sink levels.
+		entry := zapcore.Entry{
+			Level:      zapcore.WarnLevel,
+			Time:       time.Now(),
+			LoggerName: digestLogger,
+			Message:    "Error Digest",
+		}
+		if checked := d.core.Check(entry, nil); checked != nil {
+			checked.Write(
+				zap.Duration("period", period),
+				zap.Int("errors", total),
+				zap.Int("groups", len(groups)),
+				zap.Int("ungrouped", overflow),
+				zap.Array("error_groups", errorGroups(groups)),
+			)
+		}
+		return
+	}
+
+	if err := digestMessage(groups, total, overflow, period).WithWebhook(d.config.DiscordWebhook).SendDiscordEmbedWithFields(); err != nil {
+		d.onError(fmt.Errorf("failed to send error digest to Discord: %w", err))
+	}
+}
+
+func digestMessage(groups []ErrorGroup, total, overflow int, period time.Duration) webhook.DiscordMessage {
+	description := fmt.Sprintf("%d errors in %d groups in the last %s.", total, len(groups), period)
+	if overflow > 0 {
+		description += fmt.Sprintf(" %d errors were not grouped, the group limit was reached.", overflow)
+	}
+	if len(groups) > discordMaxFields {
+		description += fmt.Sprintf(" %d less frequent groups are not shown.", len(groups)-discordMaxFields)
+	}
+
+	message := webhook.NewDiscordMessage().
+		WithTitle("Error digest").
+		WithDescription(description).
+		WithColor(discordColor(zapcore.ErrorLevel))
+
+	for i, group := range groups {
+		if i == discordMaxFields {
+			break
+		}
+
+		var value strings.Builder
+		if group.Caller != "" {
+			value.WriteString(group.Caller + "\n")
+		}
+		fmt.Fprintf(&value, "first %s, last %s", group.FirstSeen.UTC().Format(time.RFC3339), group.LastSeen.UTC().Format(time.RFC3339))
+		if len(group.Frames) > 0 {
+			value.WriteString("\n```\n" + strings.Join(group.Frames, "\n") + "\n```")
+		}
+
+		message = message.WithFields(webhook.DiscordMessageField{
+			Name:  truncate(fmt.Sprintf("%d× %s", group.Count, group.Message), discordMaxTitle),
+			Value: truncate(value.String(), discordMaxFieldValue),
+		})
+	}
+
+	return message
+}
+
+// ErrorGroups returns the error groups of the current digest interval of the
+// installed logger, most frequent first, nil when the digest is disabled.
+func ErrorGroups() []ErrorGroup {
+	d := current().digest
+	if d == nil {
+		return nil
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.snapshot()
+}
+
+// stackFunctions returns the function names of the top frames of a zap
+// stacktrace, dropping file paths and line numbers so that the fingerprint
+// does not change when unrelated code moves.
+func stackFunctions(stack string, frames int) []string {
+	var functions []string
+	for _, line := range strings.Split(stack, "\n") {
+		if len(functions) == frames {
+			break
+		}
+		if line == "" || strings.HasPrefix(line, "\t") {
+			continue
+		}
+		functions = append(functions, line)
+	}
+	return functions
+}
+
+func errorFingerprint(message, caller string, frames []string) string {
+	hash := sha1.New()
+	hash.Write([]byte(message + "\x00" + caller))
+	for _, frame := range frames {
+		hash.Write([]byte("\x00" + frame))
+	}
+	return hex.EncodeToString(hash.Sum(nil))[:16]
+}
+
+// digestCore feeds the digest with the entries at the error level and above.
+type digestCore struct {
+	digest *errorDigest
+}
+
+func (c *digestCore) Enabled(level zapcore.Level) bool {
+	return level >= zapcore.ErrorLevel
+}
+
+func (c *digestCore) With([]zapcore.Field) zapcore.Core {
+	return c
+}
+
+func (c *digestCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	if c.Enabled(entry.Level) {
+		return checked.AddCore(entry, c)
+	}
+	return checked
+}
+
+func (c *digestCore) Write(entry zapcore.Entry, _ []zapcore.Field) error {
+	c.digest.add(entry)
+	return nil
+}
+
+// Sync reports the groups of the current interval, so that they are not lost
+// when the process exits after syncing the logger.
+func (c *digestCore) Sync() error {
+	c.digest.report()
+	return nil
+}
@@ -0,0 +1,163 @@
+package logger
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const testStack = `github.com/acme/billing.(*Invoices).Charge
+	/src/billing/invoices.go:88
+github.com/acme/billing.(*Worker).process
+	/src/billing/worker.go:41
+github.com/acme/billing.(*Worker).Run
+	/src/billing/worker.go:20
+runtime.goexit
+	/usr/local/go/src/runtime/asm_amd64.s:1695`
+
+func TestStackFunctions(t *testing.T) {
+	tests := []struct {
+		name   string
+		stack  string
+		frames int
+		want   []string
+	}{
+		{"empty", "", 3, nil},
+		{"no frames", testStack, 0, nil},
+		{
+			"top frames",
+			testStack,
+			2,
+			[]string{"github.com/acme/billing.(*Invoices).Charge", "github.com/acme/billing.(*Worker).process"},
+		},
+		{
+			"shorter stack",
+			testStack,
+			10,
+			[]string{
+				"github.com/acme/billing.(*Invoices).Charge",
+				"github.com/acme/billing.(*Worker).process",
+				"github.com/acme/billing.(*Worker).Run",
+				"runtime.goexit",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := stackFunctions(tt.stack, tt.frames); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestErrorFingerprint(t *testing.T) {
+	frames := []string{"billing.Charge", "billing.process"}
+	base := errorFingerprint("Error Charging Invoice", "billing/invoices.go:88", frames)
+
+	if len(base) != 16 {
+		t.Fatalf("got fingerprint %q, want 16 hex digits", base)
+	}
+
+	tests := []struct {
+		name    string
+		message string
+		caller  string
+		frames  []string
+		same    bool
+	}{
+		{"same input", "Error Charging Invoice", "billing/invoices.go:88", frames, true},
+		{"other message", "Error Refunding Invoice", "billing/invoices.go:88", frames, false},
+		{"other caller", "Error Charging Invoice", "billing/invoices.go:90", frames, false},
+		{"other frames", "Error Charging Invoice", "billing/invoices.go:88", []string{"billing.Charge", "billing.retry"}, false},
+		{"fewer frames", "Error Charging Invoice", "billing/invoices.go:88", frames[:1], false},
+		// The separators keep the parts from running into each other
+		{"shifted parts", "Error Charging Invoicebilling/invoices.go:88", "", frames, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := errorFingerprint(tt.message, tt.caller, tt.frames)
+			if (got == base) != tt.same {
+				t.Errorf("got %q for %q, base %q, want same=%v", got, tt.name, base, tt.same)
+			}
+		})
+	}
+}
+
+func TestErrorDigestGroups(t *testing.T) {
+	digest := newErrorDigest(DigestConfig{MaxGroups: 2}, newRedactor(RedactConfig{Enabled: true}), func(error) {})
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	entry := func(message string, offset time.Duration) zapcore.Entry {
+		return zapcore.Entry{
+			Level:   zapcore.ErrorLevel,
+			Time:    at.Add(offset),
+			Message: message,
+			Caller:  zapcore.EntryCaller{Defined: true, File: "/src/billing/invoices.go", Line: 88},
+			Stack:   testStack,
+		}
+	}
+
+	digest.add(entry("Error Charging Invoice", 0))
+	digest.add(entry("Error Sending Email", time.Second))
+	digest.add(entry("Error Charging Invoice", 2*time.Second))
+	digest.add(entry("Error Charging Invoice", 3*time.Second))
+	digest.add(entry("Error Exporting Report", 4*time.Second)) // Past MaxGroups
+
+	groups, overflow, _ := digest.take()
+	if overflow != 1 {
+		t.Errorf("got overflow %d, want 1", overflow)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups, want 2", len(groups))
+	}
+
+	first := groups[0]
+	if first.Message != "Error Charging Invoice" || first.Count != 3 {
+		t.Errorf("got first group %q with %d errors, want the most frequent one", first.Message, first.Count)
+	}
+	if !first.FirstSeen.Equal(at) || !first.LastSeen.Equal(at.Add(3*time.Second)) {
+		t.Errorf("got first seen %s and last seen %s", first.FirstSeen, first.LastSeen)
+	}
+	if first.Caller != "billing/invoices.go:88" || len(first.Frames) != 3 {
+		t.Errorf("got caller %q and frames %q", first.Caller, first.Frames)
+	}
+
+	if groups, overflow, _ := digest.take(); len(groups) != 0 || overflow != 0 {
+		t.Errorf("take did not start a new interval: %d groups, overflow %d", len(groups), overflow)
+	}
+}
+
+func TestErrorDigestBypassesLevels(t *testing.T) {
+	logger, err := New(
+		WithConsole(false),
+		WithMemory(10),
+		WithLevel(zapcore.ErrorLevel),
+		WithErrorDigest(DigestConfig{Interval: time.Hour}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	i := logger.Core().(*instanceCore).instance
+	defer i.close()
+	i.overrides.Store(&map[string]zapcore.Level{digestLogger: zapcore.FatalLevel})
+
+	logger.Error("Error Charging Invoice")
+	_ = logger.Sync()
+
+	entries := i.memory.snapshot()
+	if len(entries) != 2 {
+		t.Fatalf("got %d entries, want the error and the digest", len(entries))
+	}
+	digest := entries[1]
+	if digest.Message != "Error Digest" || digest.Level != zapcore.WarnLevel || digest.Logger != digestLogger {
+		t.Errorf("got %+v, want the digest", digest)
+	}
+	if digest.Fields["errors"] != int64(1) {
+		t.Errorf("got fields %v, want 1 error", digest.Fields)
+	}
+}
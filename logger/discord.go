@@ -0,0 +1,361 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/DeltaNicola/infralib/webhook"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	discordMaxTitle       = 256
+	discordMaxDescription = 4096
+	discordMaxFields      = 25
+	discordMaxFieldValue  = 1024
+)
+
+type DiscordConfig struct {
+	Webhook      string
+	DedupWindow  time.Duration
+	MaxPerMinute int
+}
+
+func discordConfigFromEnv() DiscordConfig {
+	return DiscordConfig{
+		Webhook:      os.Getenv("DISCORD_WEBHOOK_URL"),
+		DedupWindow:  envDuration("LOG_DISCORD_DEDUP_WINDOW", time.Minute),
+		MaxPerMinute: envInt("LOG_DISCORD_RATE_LIMIT", 10),
+	}
+}
+
+func (i *instance) discordLoggerCore(config DiscordConfig) (zapcore.Core, error) {
+	if config.Webhook == "" {
+		return nil, fmt.Errorf("DISCORD_WEBHOOK_URL is not set")
+	}
+
+	notifier := newDiscordNotifier(config, i.sinkErrorReporter(SinkDiscord))
+	i.onClose(notifier.close)
+
+	return i.wrapSinkCore(SinkDiscord, &discordCore{notifier: notifier}), nil
+}
+
+type discordCore struct {
+	notifier *discordNotifier
+	fields   []zapcore.Field
+}
+
+func (c *discordCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *discordCore) With(fields []zapcore.Field) zapcore.Core {
+	return &discordCore{
+		notifier: c.notifier,
+		fields:   append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *discordCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checked.AddCore(entry, c)
+}
+
+func (c *discordCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	c.notifier.notify(entry, append(c.fields[:len(c.fields):len(c.fields)], fields...))
+
+	if entry.Level > zapcore.ErrorLevel {
+		// The process is about to panic or exit
+		return c.Sync()
+	}
+	return nil
+}
+
+func (c *discordCore) Sync() error {
+	return c.notifier.sync(5 * time.Second)
+}
+
+type discordRepeat struct {
+	message webhook.DiscordMessage
+	count   int
+	until   time.Time
+}
+
+// discordNotifier collapses identical entries within the dedup window and
+// caps the number of messages sent per minute. Messages are sent from a
+// single goroutine so that logging never waits on Discord.
+type discordNotifier struct {
+	config DiscordConfig
+
+	mu          sync.Mutex
+	repeats     map[string]*discordRepeat
+	minute      time.Time
+	sent        int
+	rateLimited int
+	pending     int
+	idle        chan struct{} // Closed when pending drops to zero
+	closed      bool
+
+	queue   chan webhook.DiscordMessage
+	stop    chan struct{}
+	stats   *sinkStats
+	onError func(error)
+}
+
+func newDiscordNotifier(config DiscordConfig, onError func(error)) *discordNotifier {
+	n := &discordNotifier{
+		config:  config,
+		repeats: map[string]*discordRepeat{},
+		queue:   make(chan webhook.DiscordMessage, 100),
+		stop:    make(chan struct{}),
+		stats:   statsFor(SinkDiscord),
+		onError: onError,
+	}
+	n.stats.async.Store(true)
+
+	go n.send()
+	if config.DedupWindow > 0 {
+		go n.summarize()
+	}
+
+	return n
+}
+
+func (n *discordNotifier) notify(entry zapcore.Entry, fields []zapcore.Field) {
+	message := discordMessage(entry, fields).WithWebhook(n.config.Webhook)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.config.DedupWindow > 0 {
+		key := entry.Level.String() + "|" + entry.Caller.TrimmedPath() + "|" + entry.Message
+		if repeat, exists := n.repeats[key]; exists && entry.Time.Before(repeat.until) {
+			repeat.count++
+			return
+		}
+		n.repeats[key] = &discordRepeat{
+			message: message,
+			until:   entry.Time.Add(n.config.DedupWindow),
+		}
+	}
+
+	n.enqueue(message)
+}
+
+// enqueue must be called with mu held.
+func (n *discordNotifier) enqueue(message webhook.DiscordMessage) {
+	now := time.Now().Truncate(time.Minute)
+	if now.After(n.minute) {
+		n.minute = now
+		n.sent = 0
+		if n.rateLimited > 0 {
+			n.sent++
+			n.push(webhook.NewDiscordMessage().
+				WithTitle("Error notifications rate limited").
+				WithDescription(fmt.Sprintf("%d notifications were dropped in the previous minute.", n.rateLimited)).
+				WithColor(discordColor(zapcore.WarnLevel)).
+				WithWebhook(n.config.Webhook))
+			n.rateLimited = 0
+		}
+	}
+
+	if n.config.MaxPerMinute > 0 && n.sent >= n.config.MaxPerMinute {
+		n.rateLimited++
+		n.stats.dropped.Add(1)
+		return
+	}
+
+	n.sent++
+	n.push(message)
+}
+
+// push must be called with mu held.
+func (n *discordNotifier) push(message webhook.DiscordMessage) {
+	if n.closed {
+		n.stats.dropped.Add(1)
+		return
+	}
+
+	select {
+	case n.queue <- message:
+		if n.pending == 0 {
+			n.idle = make(chan struct{})
+		}
+		n.pending++
+	default:
+		n.rateLimited++
+		n.stats.dropped.Add(1)
+	}
+}
+
+func (n *discordNotifier) done() {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	n.pending--
+	if n.pending == 0 {
+		close(n.idle)
+	}
+}
+
+func (n *discordNotifier) send() {
+	for message := range n.queue {
+		if err := message.SendDiscordEmbedWithFields(); err != nil {
+			n.stats.failure(1, err)
+			n.onError(fmt.Errorf("failed to send log to Discord: %w", err))
+		} else {
+			n.stats.success(1)
+		}
+		n.done()
+	}
+}
+
+func (n *discordNotifier) summarize() {
+	ticker := time.NewTicker(n.config.DedupWindow / 2)
+	defer ticker.Stop()
+
+	for {
+		var now time.Time
+		select {
+		case now = <-ticker.C:
+		case <-n.stop:
+			return
+		}
+
+		n.mu.Lock()
+		for key, repeat := range n.repeats {
+			if now.Before(repeat.until) {
+				continue
+			}
+			delete(n.repeats, key)
+
+			if repeat.count > 0 {
+				n.enqueue(repeat.message.
+					WithTitle(truncate("Repeated "+repeat.message.Title, discordMaxTitle)).
+					WithDescription(fmt.Sprintf("Repeated %d times in the last %s.", repeat.count, n.config.DedupWindow)))
+			}
+		}
+		n.mu.Unlock()
+	}
+}
+
+// close waits for the pending messages, up to a timeout, and stops the
+// goroutines of the notifier. Repeats not reported yet are dropped.
+func (n *discordNotifier) close() error {
+	err := n.sync(5 * time.Second)
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if !n.closed {
+		n.closed = true
+		close(n.stop)
+		close(n.queue)
+	}
+	return err
+}
+
+func (n *discordNotifier) sync(timeout time.Duration) error {
+	n.mu.Lock()
+	pending, idle := n.pending, n.idle
+	n.mu.Unlock()
+
+	if pending == 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	select {
+	case <-idle:
+		return nil
+	case <-timer.C:
+		return fmt.Errorf("timed out waiting for Discord notifications")
+	}
+}
+
+func discordMessage(entry zapcore.Entry, fields []zapcore.Field) webhook.DiscordMessage {
+	message := webhook.NewDiscordMessage().
+		WithTitle(truncate(fmt.Sprintf("[%s] %s", entry.Level.CapitalString(), entry.Message), discordMaxTitle)).
+		WithColor(discordColor(entry.Level))
+
+	if entry.Stack != "" {
+		const fence = "```"
+		stack := truncate(entry.Stack, discordMaxDescription-2*len(fence)-2)
+		message = message.WithDescription(fence + "\n" + stack + "\n" + fence)
+	}
+
+	embedFields := make([]webhook.DiscordMessageField, 0, len(fields)+2)
+	if entry.LoggerName != "" {
+		embedFields = append(embedFields, webhook.DiscordMessageField{Name: "logger", Value: entry.LoggerName, InLine: true})
+	}
+	if entry.Caller.Defined {
+		embedFields = append(embedFields, webhook.DiscordMessageField{Name: "caller", Value: entry.Caller.TrimmedPath(), InLine: true})
+	}
+	for _, field := range fields {
+		embedFields = append(embedFields, webhook.DiscordMessageField{
+			Name:   field.Key,
+			Value:  truncate(fieldValue(field), discordMaxFieldValue),
+			InLine: true,
+		})
+	}
+
+	for i, field := range embedFields {
+		if i == discordMaxFields {
+			break
+		}
+		if field.Value == "" {
+			field.Value = "-"
+		}
+		message = message.WithFields(field)
+	}
+
+	return message
+}
+
+func fieldValue(field zapcore.Field) string {
+	encoder := zapcore.NewMapObjectEncoder()
+	field.AddTo(encoder)
+
+	value, exists := encoder.Fields[field.Key]
+	if !exists {
+		return ""
+	}
+	if s, ok := value.(string); ok {
+		return s
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprint(value)
+	}
+	return string(data)
+}
+
+func discordColor(level zapcore.Level) int {
+	switch {
+	case level >= zapcore.DPanicLevel:
+		return 0x992d22
+	case level == zapcore.ErrorLevel:
+		return 0xe74c3c
+	case level == zapcore.WarnLevel:
+		return 0xf1c40f
+	default:
+		return 0x3498db
+	}
+}
+
+func truncate(s string, max int) string {
+	if len(s) <= max {
+		return s
+	}
+	const ellipsis = "..."
+	cut := max - len(ellipsis)
+	for cut > 0 && cut < len(s) && s[cut]&0xC0 == 0x80 {
+		cut-- // Don't split a UTF-8 sequence
+	}
+	return s[:cut] + ellipsis
+}
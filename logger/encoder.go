@@ -0,0 +1,128 @@
+package logger
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	FormatJSON    = "json"
+	FormatConsole = "console"
+	FormatLogfmt  = "logfmt"
+	FormatECS     = "ecs"
+
+	ecsVersion = "8.11.0"
+)
+
+// The caller is written by ecsEncoder, ECS splits it in file name and line.
+var ecsEncoderConfig = zapcore.EncoderConfig{
+	TimeKey:       "@timestamp",
+	LevelKey:      "log.level",
+	NameKey:       "log.logger",
+	MessageKey:    "message",
+	FunctionKey:   "log.origin.function",
+	StacktraceKey: "error.stack_trace",
+	EncodeTime:    zapcore.ISO8601TimeEncoder,
+	EncodeLevel:   zapcore.LowercaseLevelEncoder,
+}
+
+var logfmtEncoderConfig = zapcore.EncoderConfig{
+	TimeKey:       "time",
+	LevelKey:      "level",
+	NameKey:       "logger",
+	MessageKey:    "msg",
+	CallerKey:     "caller",
+	FunctionKey:   "function",
+	StacktraceKey: "stacktrace",
+	EncodeTime:    zapcore.ISO8601TimeEncoder,
+	EncodeLevel:   zapcore.LowercaseLevelEncoder,
+	EncodeCaller:  zapcore.ShortCallerEncoder,
+}
+
+// sinkFormat returns the format of sink in the Config of i. The console sink
+// defaults to the console format in development mode, other sinks to JSON.
+func (i *instance) sinkFormat(sink string) string {
+	config := i.config
+	if format := config.Formats[sink]; format != "" {
+		return strings.ToLower(format)
+	}
+	if config.Format != "" {
+		return strings.ToLower(config.Format)
+	}
+	if sink == SinkConsole && config.Development {
+		return FormatConsole
+	}
+	return FormatJSON
+}
+
+func encoderConfigFor(format string) zapcore.EncoderConfig {
+	switch format {
+	case FormatECS:
+		return ecsEncoderConfig
+	case FormatLogfmt:
+		return logfmtEncoderConfig
+	default:
+		return encoderConfig
+	}
+}
+
+// newEncoder builds the encoder for format. Only the console sink gets
+// colored levels, other sinks may not write to a terminal.
+func newEncoder(sink, format string) (zapcore.Encoder, error) {
+	switch format {
+	case FormatJSON:
+		return zapcore.NewJSONEncoder(encoderConfig), nil
+	case FormatConsole:
+		config := encoderConfig
+		config.EncodeTime = zapcore.ISO8601TimeEncoder
+		if sink == SinkConsole {
+			config.EncodeLevel = zapcore.CapitalColorLevelEncoder
+		}
+		return zapcore.NewConsoleEncoder(config), nil
+	case FormatLogfmt:
+		return newLogfmtEncoder(logfmtEncoderConfig), nil
+	case FormatECS:
+		return newECSEncoder(), nil
+	default:
+		return nil, fmt.Errorf("unknown log format %q for %s sink", format, sink)
+	}
+}
+
+// sinkEncoder returns the encoder configured for sink, falling back to JSON
+// when the configured format is unknown.
+func (i *instance) sinkEncoder(sink string) (zapcore.Encoder, error) {
+	encoder, err := newEncoder(sink, i.sinkFormat(sink))
+	if err != nil {
+		return zapcore.NewJSONEncoder(encoderConfig), err
+	}
+	return encoder, nil
+}
+
+type ecsEncoder struct {
+	zapcore.Encoder
+}
+
+func newECSEncoder() zapcore.Encoder {
+	encoder := zapcore.NewJSONEncoder(ecsEncoderConfig)
+	encoder.AddString("ecs.version", ecsVersion)
+	return ecsEncoder{Encoder: encoder}
+}
+
+func (e ecsEncoder) Clone() zapcore.Encoder {
+	return ecsEncoder{Encoder: e.Encoder.Clone()}
+}
+
+func (e ecsEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	if entry.Caller.Defined {
+		fields = append([]zapcore.Field{
+			zap.String("log.origin.file.name", filepath.Base(entry.Caller.File)),
+			zap.Int("log.origin.file.line", entry.Caller.Line),
+		}, fields...)
+	}
+	return e.Encoder.EncodeEntry(entry, fields)
+}
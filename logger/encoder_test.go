@@ -0,0 +1,168 @@
+package logger
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+var testEntry = zapcore.Entry{
+	Level:   zapcore.WarnLevel,
+	Time:    time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC),
+	Message: "Disk Almost Full",
+	Caller: zapcore.EntryCaller{
+		Defined:  true,
+		File:     "/src/app/storage/disk.go",
+		Line:     42,
+		Function: "app/storage.checkDisk",
+	},
+}
+
+func TestLogfmtEncoder(t *testing.T) {
+	tests := []struct {
+		name   string
+		fields []zapcore.Field
+		want   string
+	}{
+		{
+			"no fields",
+			nil,
+			`level=warn time=2024-05-01T12:30:00.000Z caller=storage/disk.go:42 function=app/storage.checkDisk msg="Disk Almost Full"` + "\n",
+		},
+		{
+			"plain values",
+			[]zapcore.Field{zap.String("disk", "sda"), zap.Int("percent", 95), zap.Bool("critical", false)},
+			`level=warn time=2024-05-01T12:30:00.000Z caller=storage/disk.go:42 function=app/storage.checkDisk msg="Disk Almost Full" disk=sda percent=95 critical=false` + "\n",
+		},
+		{
+			"quoted values",
+			[]zapcore.Field{zap.String("path", "/mnt/my disk"), zap.String("empty", ""), zap.String("quote", `a"b`)},
+			`level=warn time=2024-05-01T12:30:00.000Z caller=storage/disk.go:42 function=app/storage.checkDisk msg="Disk Almost Full" path="/mnt/my disk" empty="" quote="a\"b"` + "\n",
+		},
+		{
+			"keys",
+			[]zapcore.Field{zap.String("a key", "v"), zap.String("a=b", "v")},
+			`level=warn time=2024-05-01T12:30:00.000Z caller=storage/disk.go:42 function=app/storage.checkDisk msg="Disk Almost Full" a_key=v a_b=v` + "\n",
+		},
+		{
+			"nested",
+			[]zapcore.Field{zap.Any("mounts", map[string]int{"sda": 1}), zap.Strings("tags", []string{"a", "b"})},
+			`level=warn time=2024-05-01T12:30:00.000Z caller=storage/disk.go:42 function=app/storage.checkDisk msg="Disk Almost Full" mounts="{\"sda\":1}" tags="[\"a\",\"b\"]"` + "\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := newLogfmtEncoder(logfmtEncoderConfig).EncodeEntry(testEntry, tt.fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer buf.Free()
+
+			if got := buf.String(); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestECSEncoder(t *testing.T) {
+	noCaller := testEntry
+	noCaller.Caller = zapcore.EntryCaller{}
+
+	named := testEntry
+	named.LoggerName = "storage"
+
+	tests := []struct {
+		name   string
+		entry  zapcore.Entry
+		fields []zapcore.Field
+		want   map[string]interface{}
+	}{
+		{
+			"caller",
+			testEntry,
+			nil,
+			map[string]interface{}{
+				"@timestamp":           "2024-05-01T12:30:00.000Z",
+				"log.level":            "warn",
+				"log.origin.function":  "app/storage.checkDisk",
+				"message":              "Disk Almost Full",
+				"ecs.version":          ecsVersion,
+				"log.origin.file.name": "disk.go",
+				"log.origin.file.line": float64(42),
+			},
+		},
+		{
+			"no caller",
+			noCaller,
+			[]zapcore.Field{zap.String("disk", "sda")},
+			map[string]interface{}{
+				"@timestamp":  "2024-05-01T12:30:00.000Z",
+				"log.level":   "warn",
+				"message":     "Disk Almost Full",
+				"ecs.version": ecsVersion,
+				"disk":        "sda",
+			},
+		},
+		{
+			"logger name",
+			named,
+			nil,
+			map[string]interface{}{
+				"@timestamp":           "2024-05-01T12:30:00.000Z",
+				"log.level":            "warn",
+				"log.logger":           "storage",
+				"log.origin.function":  "app/storage.checkDisk",
+				"message":              "Disk Almost Full",
+				"ecs.version":          ecsVersion,
+				"log.origin.file.name": "disk.go",
+				"log.origin.file.line": float64(42),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			buf, err := newECSEncoder().EncodeEntry(tt.entry, tt.fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer buf.Free()
+
+			var got map[string]interface{}
+			if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+				t.Fatalf("invalid JSON %s: %v", buf.String(), err)
+			}
+			if !equalJSON(t, got, tt.want) {
+				t.Errorf("got  %s\nwant %v", buf.String(), tt.want)
+			}
+		})
+	}
+}
+
+func TestSinkFormat(t *testing.T) {
+	tests := []struct {
+		name   string
+		config Config
+		sink   string
+		want   string
+	}{
+		{"default", Config{}, SinkFile, FormatJSON},
+		{"development console", Config{Development: true}, SinkConsole, FormatConsole},
+		{"development file", Config{Development: true}, SinkFile, FormatJSON},
+		{"global", Config{Format: "LOGFMT"}, SinkFile, FormatLogfmt},
+		{"sink", Config{Format: FormatLogfmt, Formats: map[string]string{SinkFile: FormatECS}}, SinkFile, FormatECS},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := newInstance(tt.config).sinkFormat(tt.sink); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
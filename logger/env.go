@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"os"
+	"strconv"
+	"time"
+)
+
+func envString(name, fallback string) string {
+	value, exists := os.LookupEnv(name)
+	if !exists || value == "" {
+		return fallback
+	}
+	return value
+}
+
+func envBool(name string, fallback bool) bool {
+	value, exists := os.LookupEnv(name)
+	if !exists {
+		return fallback
+	}
+	parsed, err := strconv.ParseBool(value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+func envInt(name string, fallback int) int {
+	value, exists := os.LookupEnv(name)
+	if !exists {
+		return fallback
+	}
+	parsed, err := strconv.Atoi(value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
+
+func envDuration(name string, fallback time.Duration) time.Duration {
+	value, exists := os.LookupEnv(name)
+	if !exists {
+		return fallback
+	}
+	parsed, err := time.ParseDuration(value)
+	if err != nil {
+		return fallback
+	}
+	return parsed
+}
@@ -1,30 +1,210 @@
 package logger
 
 import (
+	"errors"
+	"fmt"
 	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
 
-	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 	"gopkg.in/natefinch/lumberjack.v2"
 )
 
-func fileLoggerCore() zapcore.Core {
-	logFilePath, exists := os.LookupEnv("LOG_FILE_PATH")
-	if !exists {
-		logFilePath = "./app.log"
+const (
+	RotateNone   = ""
+	RotateHourly = "hourly"
+	RotateDaily  = "daily"
+)
+
+type FileConfig struct {
+	Path       string
+	MaxSize    int // In MB
+	MaxBackups int
+	MaxAge     int // In days
+	Compress   bool
+
+	RotateInterval string // RotateNone, RotateHourly or RotateDaily
+	RotateOnSIGHUP bool
+	// SplitByLevel also writes error and higher entries to a second file,
+	// e.g. app.error.log next to app.log.
+	SplitByLevel bool
+}
+
+func fileConfigFromEnv() FileConfig {
+	return FileConfig{
+		Path:       envString("LOG_FILE_PATH", "./app.log"),
+		MaxSize:    envInt("LOG_FILE_MAX_SIZE", 10),
+		MaxBackups: envInt("LOG_FILE_MAX_BACKUPS", 3),
+		MaxAge:     envInt("LOG_FILE_MAX_AGE", 28),
+		Compress:   envBool("LOG_FILE_COMPRESS", true),
+
+		RotateInterval: strings.ToLower(os.Getenv("LOG_FILE_ROTATE_INTERVAL")),
+		RotateOnSIGHUP: envBool("LOG_FILE_ROTATE_ON_SIGHUP", false),
+		SplitByLevel:   envBool("LOG_FILE_SPLIT_BY_LEVEL", false),
+	}
+}
+
+func (i *instance) fileLoggerCore(config FileConfig) (zapcore.Core, error) {
+	var interval time.Duration
+	switch config.RotateInterval {
+	case RotateNone:
+	case RotateHourly:
+		interval = time.Hour
+	case RotateDaily:
+		interval = 24 * time.Hour
+	default:
+		return nil, fmt.Errorf("unknown log file rotate interval %q", config.RotateInterval)
+	}
+
+	encoder, encoderErr := i.sinkEncoder(SinkFile)
+
+	files := []*lumberjack.Logger{newLumberjack(config, config.Path)}
+	if config.SplitByLevel {
+		ext := filepath.Ext(config.Path)
+		files = append(files, newLumberjack(config, strings.TrimSuffix(config.Path, ext)+".error"+ext))
+	}
+
+	var cores []zapcore.Core
+	for n, file := range files {
+		core := zapcore.NewCore(encoder.Clone(), zapcore.AddSync(newRotatingFile(file, interval)), zapcore.DebugLevel)
+		if n > 0 {
+			core = errorOnlyCore{Core: core}
+		}
+		cores = append(cores, core)
 	}
 
-	lumberjack := &lumberjack.Logger{
-		Filename:   logFilePath,
-		MaxSize:    10, // In MB
-		MaxBackups: 3,
-		MaxAge:     28, // In days
-		Compress:   true,
+	if config.RotateOnSIGHUP {
+		rotateOnSIGHUP(files)
 	}
+	i.onClose(func() error {
+		stopRotateOnSIGHUP(files)
+		var errs []error
+		for _, file := range files {
+			errs = append(errs, file.Close())
+		}
+		return errors.Join(errs...)
+	})
+
+	return i.wrapSinkCore(SinkFile, zapcore.NewTee(cores...)), encoderErr
+}
+
+func newLumberjack(config FileConfig, path string) *lumberjack.Logger {
+	return &lumberjack.Logger{
+		Filename:   path,
+		MaxSize:    config.MaxSize,
+		MaxBackups: config.MaxBackups,
+		MaxAge:     config.MaxAge,
+		Compress:   config.Compress,
+	}
+}
+
+type errorOnlyCore struct {
+	zapcore.Core
+}
+
+func (c errorOnlyCore) With(fields []zapcore.Field) zapcore.Core {
+	return errorOnlyCore{Core: c.Core.With(fields)}
+}
+
+func (c errorOnlyCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	if entry.Level < zapcore.ErrorLevel {
+		return nil
+	}
+	return c.Core.Write(entry, fields)
+}
 
-	return zapcore.NewCore(
-		zapcore.NewJSONEncoder(encoderConfig),
-		zapcore.AddSync(lumberjack),
-		zap.InfoLevel,
-	)
+// rotatingFile rotates the file on every interval boundary, on top of the
+// size based rotation done by lumberjack.
+type rotatingFile struct {
+	*lumberjack.Logger
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+func newRotatingFile(file *lumberjack.Logger, interval time.Duration) zapcore.WriteSyncer {
+	if interval == 0 {
+		return zapcore.AddSync(file)
+	}
+
+	return &rotatingFile{
+		Logger:   file,
+		interval: interval,
+		next:     nextBoundary(time.Now(), interval),
+	}
+}
+
+func nextBoundary(t time.Time, interval time.Duration) time.Time {
+	if interval == 24*time.Hour {
+		year, month, day := t.Date()
+		return time.Date(year, month, day+1, 0, 0, 0, 0, t.Location())
+	}
+	return t.Truncate(interval).Add(interval)
+}
+
+func (f *rotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	if now := time.Now(); !now.Before(f.next) {
+		f.next = nextBoundary(now, f.interval)
+		f.mu.Unlock()
+		if err := f.Logger.Rotate(); err != nil {
+			return 0, fmt.Errorf("failed to rotate log file: %w", err)
+		}
+	} else {
+		f.mu.Unlock()
+	}
+
+	return f.Logger.Write(p)
+}
+
+func (f *rotatingFile) Sync() error {
+	return nil
+}
+
+var (
+	sighupOnce  sync.Once
+	sighupMu    sync.Mutex
+	sighupFiles []*lumberjack.Logger
+)
+
+// rotateOnSIGHUP replaces the files rotated when the process receives SIGHUP,
+// as sent by logrotate and most process supervisors.
+func rotateOnSIGHUP(files []*lumberjack.Logger) {
+	sighupMu.Lock()
+	sighupFiles = files
+	sighupMu.Unlock()
+
+	sighupOnce.Do(func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGHUP)
+
+		go func() {
+			for range signals {
+				sighupMu.Lock()
+				for _, file := range sighupFiles {
+					if err := file.Rotate(); err != nil {
+						reportSinkError(SinkFile, fmt.Errorf("failed to rotate log file on SIGHUP: %w", err))
+					}
+				}
+				sighupMu.Unlock()
+			}
+		}()
+	})
+}
+
+// stopRotateOnSIGHUP stops rotating files, unless other files replaced them
+// since, so that a closed file is not reopened by a rotation.
+func stopRotateOnSIGHUP(files []*lumberjack.Logger) {
+	sighupMu.Lock()
+	defer sighupMu.Unlock()
+
+	if len(sighupFiles) > 0 && sighupFiles[0] == files[0] {
+		sighupFiles = nil
+	}
 }
@@ -0,0 +1,140 @@
+package logger
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// instance holds the state of a logger built by New: its Config, sink
+// levels, logger level overrides, redaction, memory sink, error digest and
+// audit logger. Only the instance installed by Install is reachable from the
+// package functions, like SetLevel and MemoryHandler.
+type instance struct {
+	config      Config
+	errorOutput zapcore.WriteSyncer
+	redactor    *redactor
+
+	levelsMu  sync.RWMutex
+	levels    map[string]zap.AtomicLevel
+	overrides atomic.Pointer[map[string]zapcore.Level]
+
+	memory       *ringBuffer
+	digest       *errorDigest
+	audit        zapcore.Core
+	auditWriters []*auditWriter
+
+	closers   []func() error
+	closeOnce sync.Once
+	closeErr  error
+}
+
+func newInstance(config Config) *instance {
+	errorOutput := config.ErrorOutput
+	if errorOutput == nil {
+		errorOutput = defaultErrorOutput
+	}
+
+	return &instance{
+		config:      config,
+		errorOutput: errorOutput,
+		redactor:    newRedactor(config.Redact),
+		levels:      map[string]zap.AtomicLevel{},
+		audit:       zapcore.NewNopCore(),
+	}
+}
+
+// onClose adds a function flushing and releasing a sink when the instance
+// is closed.
+func (i *instance) onClose(closer func() error) {
+	i.closers = append(i.closers, closer)
+}
+
+// close reports and stops the error digest, then flushes and closes the
+// sinks and the audit destinations. Only the first call does something.
+func (i *instance) close() error {
+	i.closeOnce.Do(func() {
+		if i.digest != nil {
+			i.digest.stop()
+		}
+
+		var errs []error
+		for _, closer := range i.closers {
+			errs = append(errs, closer())
+		}
+		for _, writer := range i.auditWriters {
+			errs = append(errs, writer.close())
+		}
+		i.closeErr = errors.Join(errs...)
+	})
+	return i.closeErr
+}
+
+var (
+	defaultInstance = newInstance(DefaultConfig())
+	installed       atomic.Pointer[instance]
+)
+
+// current returns the installed instance, an instance without sinks before
+// InitLogger or Install is called.
+func current() *instance {
+	if i := installed.Load(); i != nil {
+		return i
+	}
+	return defaultInstance
+}
+
+// instanceCore is the core of the loggers built by New, it lets Install find
+// their instance.
+type instanceCore struct {
+	zapcore.Core
+	instance *instance
+}
+
+func (c *instanceCore) With(fields []zapcore.Field) zapcore.Core {
+	return &instanceCore{Core: c.Core.With(fields), instance: c.instance}
+}
+
+func instanceOf(logger *zap.Logger) (*instance, error) {
+	core, ok := logger.Core().(*instanceCore)
+	if !ok {
+		return nil, errors.New("logger was not built by logger.New")
+	}
+	return core.instance, nil
+}
+
+// Install replaces Logger with logger, which must have been built by New,
+// and makes the package functions, like SetLevel, ApplyLevels,
+// MemoryHandler and ErrorGroups, and Audit act on its sinks. The logger
+// installed before is closed, as by Close, and its errors are returned.
+func Install(logger *zap.Logger) error {
+	i, err := instanceOf(logger)
+	if err != nil {
+		return err
+	}
+
+	Logger = logger
+	if previous := installed.Swap(i); previous != nil && previous != i {
+		return previous.close()
+	}
+	return nil
+}
+
+// Close reports the error digest of logger, which must have been built by
+// New, then flushes and closes its sinks and audit destinations. Entries
+// logged afterwards are lost. Closing the installed logger makes Logger
+// discard everything, as before InitLogger.
+func Close(logger *zap.Logger) error {
+	i, err := instanceOf(logger)
+	if err != nil {
+		return err
+	}
+
+	if installed.CompareAndSwap(i, nil) {
+		Logger = zap.NewNop()
+	}
+	return i.close()
+}
@@ -0,0 +1,141 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// pushServer counts the requests it receives.
+func pushServer(t *testing.T) (*httptest.Server, *atomic.Int32) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func TestNewLoggersAreIndependent(t *testing.T) {
+	first, err := New(WithConsole(false), WithMemory(10), WithLevel(zapcore.WarnLevel))
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := New(WithConsole(false), WithMemory(10), WithRedaction(RedactConfig{}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer Close(first)
+	defer Close(second)
+
+	for _, logger := range []*zap.Logger{first, second} {
+		logger.Info("Started", zap.String("password", "hunter2"))
+	}
+
+	firstEntries := first.Core().(*instanceCore).instance.memory.snapshot()
+	secondEntries := second.Core().(*instanceCore).instance.memory.snapshot()
+	if len(firstEntries) != 0 {
+		t.Errorf("got %d entries below the level of the first logger", len(firstEntries))
+	}
+	if len(secondEntries) != 1 || secondEntries[0].Fields["password"] != "hunter2" {
+		t.Errorf("got %+v, want the entry unredacted in the second logger only", secondEntries)
+	}
+
+	// Not installed, the package functions act on neither
+	if err := SetLevel(SinkMemory, zapcore.DebugLevel); err == nil {
+		t.Error("SetLevel changed a logger that is not installed")
+	}
+	if RecentLogs() != nil {
+		t.Error("RecentLogs returned the entries of a logger that is not installed")
+	}
+}
+
+func TestInstall(t *testing.T) {
+	previousLogger, previous := Logger, installed.Load()
+	t.Cleanup(func() {
+		Logger = previousLogger
+		installed.Store(previous)
+	})
+
+	if err := Install(zap.NewNop()); err == nil {
+		t.Error("installed a logger not built by New")
+	}
+
+	server, requests := pushServer(t)
+	first, err := New(WithConsole(false), WithLoki(LokiConfig{Endpoint: server.URL, Batch: BatchConfig{FlushInterval: time.Hour}}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Install(first); err != nil {
+		t.Fatal(err)
+	}
+	Logger.Info("Started")
+
+	second, err := New(WithConsole(false), WithMemory(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Install(second); err != nil {
+		t.Fatal(err)
+	}
+	if requests.Load() != 1 {
+		t.Errorf("got %d Loki pushes, want the replaced logger flushed", requests.Load())
+	}
+
+	Logger.Info("Installed")
+	if got := recentMessages(); got != "Installed" {
+		t.Errorf("got %q from the installed memory sink", got)
+	}
+
+	if err := Close(second); err != nil {
+		t.Fatal(err)
+	}
+	if current() != defaultInstance || Logger.Core().Enabled(zapcore.FatalLevel) {
+		t.Error("closing the installed logger did not uninstall it")
+	}
+}
+
+func TestCloseStopsSinks(t *testing.T) {
+	server, requests := pushServer(t)
+	before := runtime.NumGoroutine()
+
+	for n := 0; n < 3; n++ {
+		logger, err := New(
+			WithConsole(false),
+			WithLoki(LokiConfig{Endpoint: server.URL}),
+			WithOTLP(OTLPConfig{Endpoint: server.URL, Timeout: time.Second}),
+			WithDiscord(DiscordConfig{Webhook: server.URL, DedupWindow: time.Minute}),
+			WithErrorDigest(DigestConfig{}),
+		)
+		if err != nil {
+			t.Fatal(err)
+		}
+		logger.Info("Started")
+
+		if err := Close(logger); err != nil {
+			t.Fatal(err)
+		}
+		if err := Close(logger); err != nil {
+			t.Errorf("got error %v closing twice", err)
+		}
+	}
+
+	if got := requests.Load(); got != 6 {
+		t.Errorf("got %d requests, want the Loki and OTLP entries flushed on close", got)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > before {
+		if time.Now().After(deadline) {
+			t.Fatalf("got %d goroutines, %d before the loggers", runtime.NumGoroutine(), before)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
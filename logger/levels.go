@@ -0,0 +1,276 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const (
+	SinkConsole    = "console"
+	SinkFile       = "file"
+	SinkOpenSearch = "open_search"
+	SinkDiscord    = "discord"
+	SinkSyslog     = "syslog"
+	SinkLoki       = "loki"
+	SinkOTLP       = "otlp"
+	SinkMemory     = "memory"
+)
+
+// Sinks that are only meant for some entries have their own default, which
+// Config.Level does not change.
+var sinkDefaultLevels = map[string]zapcore.Level{
+	SinkDiscord: zapcore.ErrorLevel,
+}
+
+func hasDefaultLevel(sink string) bool {
+	_, exists := sinkDefaultLevels[sink]
+	return exists
+}
+
+// configuredLevel returns the level of sink in the Config of i.
+func (i *instance) configuredLevel(sink string) zapcore.Level {
+	if level, exists := i.config.Levels[sink]; exists {
+		return level
+	}
+	if level, exists := sinkDefaultLevels[sink]; exists {
+		return level
+	}
+	return i.config.Level
+}
+
+// sinkLevel returns the level shared by every core of a sink, creating it from
+// the Config the first time the sink is seen.
+func (i *instance) sinkLevel(sink string) zap.AtomicLevel {
+	i.levelsMu.Lock()
+	defer i.levelsMu.Unlock()
+
+	level, exists := i.levels[sink]
+	if !exists {
+		level = zap.NewAtomicLevelAt(i.configuredLevel(sink))
+		i.levels[sink] = level
+	}
+	return level
+}
+
+// GetLevel returns the level of a sink of the installed logger.
+func GetLevel(sink string) (zapcore.Level, bool) {
+	i := current()
+	i.levelsMu.RLock()
+	defer i.levelsMu.RUnlock()
+
+	level, exists := i.levels[sink]
+	if !exists {
+		return zapcore.InvalidLevel, false
+	}
+	return level.Level(), true
+}
+
+// SetLevel changes the level of a sink of the installed logger at runtime. An
+// empty sink changes the level of every sink without a default level of its
+// own.
+func SetLevel(sink string, level zapcore.Level) error {
+	i := current()
+	i.levelsMu.RLock()
+	defer i.levelsMu.RUnlock()
+
+	if sink == "" {
+		for sink, atomic := range i.levels {
+			if !hasDefaultLevel(sink) {
+				atomic.SetLevel(level)
+			}
+		}
+		return nil
+	}
+
+	atomic, exists := i.levels[sink]
+	if !exists {
+		return fmt.Errorf("unknown log sink %q", sink)
+	}
+	atomic.SetLevel(level)
+
+	return nil
+}
+
+func Levels() map[string]string {
+	i := current()
+	i.levelsMu.RLock()
+	defer i.levelsMu.RUnlock()
+
+	result := make(map[string]string, len(i.levels))
+	for sink, level := range i.levels {
+		result[sink] = level.String()
+	}
+	return result
+}
+
+// LevelHandler serves the sink levels as JSON on GET and changes them on PUT
+// or POST with a body like {"sink": "console", "level": "debug"}. Omitting
+// the sink changes every sink.
+func LevelHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.Method {
+		case http.MethodGet:
+		case http.MethodPut, http.MethodPost:
+			var req struct {
+				Sink  string `json:"sink"`
+				Level string `json:"level"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, fmt.Sprintf("invalid request body: %v", err), http.StatusBadRequest)
+				return
+			}
+
+			level, err := zapcore.ParseLevel(req.Level)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			if err := SetLevel(req.Sink, level); err != nil {
+				http.Error(w, err.Error(), http.StatusNotFound)
+				return
+			}
+		default:
+			w.Header().Set("Allow", "GET, PUT, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Levels())
+	})
+}
+
+// loggerLevel returns the override for a named logger, looking at its parents
+// too: an override for "kafka" applies to "kafka.consumer".
+func (i *instance) loggerLevel(name string) (zapcore.Level, bool) {
+	overrides := i.overrides.Load()
+	if overrides == nil || len(*overrides) == 0 {
+		return zapcore.InvalidLevel, false
+	}
+
+	for name != "" {
+		if level, exists := (*overrides)[name]; exists {
+			return level, true
+		}
+		i := strings.LastIndexByte(name, '.')
+		if i < 0 {
+			break
+		}
+		name = name[:i]
+	}
+	return zapcore.InvalidLevel, false
+}
+
+func (i *instance) minLoggerLevel() (zapcore.Level, bool) {
+	overrides := i.overrides.Load()
+	if overrides == nil || len(*overrides) == 0 {
+		return zapcore.InvalidLevel, false
+	}
+
+	min := zapcore.FatalLevel
+	for _, level := range *overrides {
+		if level < min {
+			min = level
+		}
+	}
+	return min, true
+}
+
+// SetLoggerLevel overrides the level of every sink of the installed logger
+// for the logger with the given name, as set by zap.Logger.Named, and for its
+// children.
+func SetLoggerLevel(name string, level zapcore.Level) {
+	i := current()
+	for {
+		previous := i.overrides.Load()
+		next := map[string]zapcore.Level{}
+		if previous != nil {
+			for k, v := range *previous {
+				next[k] = v
+			}
+		}
+		next[name] = level
+		if i.overrides.CompareAndSwap(previous, &next) {
+			return
+		}
+	}
+}
+
+func ClearLoggerLevels() {
+	current().overrides.Store(nil)
+}
+
+type LevelConfig struct {
+	Level   string            `json:"level"`
+	Sinks   map[string]string `json:"sinks"`
+	Loggers map[string]string `json:"loggers"`
+}
+
+// ApplyLevels replaces the levels of the installed logger with the ones in
+// config. Anything config does not mention goes back to its default.
+func ApplyLevels(config LevelConfig) error {
+	parse := func(value string) (zapcore.Level, error) {
+		level, err := zapcore.ParseLevel(value)
+		if err != nil {
+			return level, fmt.Errorf("invalid log level %q: %w", value, err)
+		}
+		return level, nil
+	}
+
+	var global *zapcore.Level
+	if config.Level != "" {
+		level, err := parse(config.Level)
+		if err != nil {
+			return err
+		}
+		global = &level
+	}
+
+	sinkLevels := make(map[string]zapcore.Level, len(config.Sinks))
+	for sink, value := range config.Sinks {
+		level, err := parse(value)
+		if err != nil {
+			return err
+		}
+		sinkLevels[sink] = level
+	}
+
+	overrides := make(map[string]zapcore.Level, len(config.Loggers))
+	for name, value := range config.Loggers {
+		level, err := parse(value)
+		if err != nil {
+			return err
+		}
+		overrides[name] = level
+	}
+
+	i := current()
+	i.levelsMu.RLock()
+	for sink, atomic := range i.levels {
+		switch level, exists := sinkLevels[sink]; {
+		case exists:
+			atomic.SetLevel(level)
+		case global != nil && !hasDefaultLevel(sink):
+			atomic.SetLevel(*global)
+		default:
+			atomic.SetLevel(i.configuredLevel(sink))
+		}
+	}
+	i.levelsMu.RUnlock()
+
+	i.overrides.Store(&overrides)
+
+	return nil
+}
+
+// ResetLevels puts every sink of the installed logger back to the level of
+// its Config and removes the logger overrides.
+func ResetLevels() {
+	_ = ApplyLevels(LevelConfig{})
+}
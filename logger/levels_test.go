@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// installTestLogger installs a logger built by New with a memory sink and
+// puts the previous one back when the test ends.
+func installTestLogger(t *testing.T, opts ...Option) *zap.Logger {
+	t.Helper()
+
+	logger, err := New(append([]Option{WithConsole(false), WithMemory(100)}, opts...)...)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	previousLogger, previous := Logger, installed.Load()
+	if err := Install(logger); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		Logger = previousLogger
+		installed.Swap(previous).close()
+	})
+
+	return logger
+}
+
+// recentMessages returns the messages kept by the memory sink, joined by
+// commas.
+func recentMessages() string {
+	var messages []string
+	for _, entry := range RecentLogs() {
+		messages = append(messages, entry.Message)
+	}
+	return strings.Join(messages, ",")
+}
+
+func TestSinkLevels(t *testing.T) {
+	logger := installTestLogger(t, WithLevel(zapcore.ErrorLevel), WithSinkLevel(SinkMemory, zapcore.InfoLevel))
+
+	logger.Debug("debug")
+	logger.Info("info")
+	if got := recentMessages(); got != "info" {
+		t.Errorf("got %q, want the sink level to apply", got)
+	}
+
+	if level, exists := GetLevel(SinkMemory); !exists || level != zapcore.InfoLevel {
+		t.Errorf("got level %s, %v", level, exists)
+	}
+	if _, exists := GetLevel(SinkConsole); exists {
+		t.Error("got a level for a disabled sink")
+	}
+
+	if err := SetLevel(SinkMemory, zapcore.DebugLevel); err != nil {
+		t.Fatal(err)
+	}
+	logger.Debug("debug after SetLevel")
+	if got := recentMessages(); got != "info,debug after SetLevel" {
+		t.Errorf("got %q, want SetLevel to apply", got)
+	}
+
+	if err := SetLevel("unknown", zapcore.DebugLevel); err == nil {
+		t.Error("got no error for an unknown sink")
+	}
+}
+
+func TestApplyLevels(t *testing.T) {
+	logger := installTestLogger(t, WithLevel(zapcore.WarnLevel))
+
+	tests := []struct {
+		name   string
+		config LevelConfig
+		want   zapcore.Level
+		err    bool
+	}{
+		{name: "global", config: LevelConfig{Level: "debug"}, want: zapcore.DebugLevel},
+		{name: "sink", config: LevelConfig{Level: "debug", Sinks: map[string]string{SinkMemory: "error"}}, want: zapcore.ErrorLevel},
+		{name: "defaults", config: LevelConfig{}, want: zapcore.WarnLevel},
+		{name: "invalid", config: LevelConfig{Level: "loud"}, want: zapcore.WarnLevel, err: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := ApplyLevels(tt.config); (err != nil) != tt.err {
+				t.Fatalf("got error %v, want error %v", err, tt.err)
+			}
+			if level, _ := GetLevel(SinkMemory); level != tt.want {
+				t.Errorf("got level %s, want %s", level, tt.want)
+			}
+			if !logger.Core().Enabled(tt.want) || logger.Core().Enabled(tt.want-1) {
+				t.Errorf("the logger is not enabled from %s", tt.want)
+			}
+		})
+	}
+}
+
+func TestLevelHandler(t *testing.T) {
+	installTestLogger(t)
+
+	tests := []struct {
+		method string
+		body   string
+		status int
+		want   string
+	}{
+		{http.MethodGet, "", http.StatusOK, `{"memory":"info"}`},
+		{http.MethodPut, `{"sink":"memory","level":"debug"}`, http.StatusOK, `{"memory":"debug"}`},
+		{http.MethodPost, `{"level":"warn"}`, http.StatusOK, `{"memory":"warn"}`},
+		{http.MethodPut, `{"sink":"unknown","level":"debug"}`, http.StatusNotFound, ""},
+		{http.MethodPut, `{"level":"loud"}`, http.StatusBadRequest, ""},
+		{http.MethodDelete, "", http.StatusMethodNotAllowed, ""},
+	}
+
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		LevelHandler().ServeHTTP(recorder, httptest.NewRequest(tt.method, "/levels", strings.NewReader(tt.body)))
+
+		if recorder.Code != tt.status {
+			t.Errorf("%s %s: got status %d, want %d", tt.method, tt.body, recorder.Code, tt.status)
+		}
+		if got := strings.TrimSpace(recorder.Body.String()); tt.want != "" && got != tt.want {
+			t.Errorf("%s %s: got %s, want %s", tt.method, tt.body, got, tt.want)
+		}
+	}
+}
+
+func TestLoggerLevels(t *testing.T) {
+	logger := installTestLogger(t, WithLevel(zapcore.WarnLevel))
+	kafka := logger.Named("kafka")
+
+	tests := []struct {
+		name  string
+		setup func()
+		log   func()
+		want  string
+	}{
+		{"sink level", func() {}, func() { kafka.Info("a"); logger.Warn("b") }, "b"},
+		{
+			"louder",
+			func() { SetLoggerLevel("kafka", zapcore.DebugLevel) },
+			func() { kafka.Named("consumer").Debug("a"); logger.Info("b"); logger.Named("kafkaesque").Info("c") },
+			"a",
+		},
+		{
+			"quieter",
+			func() { SetLoggerLevel("kafka", zapcore.ErrorLevel) },
+			func() { kafka.Warn("a"); kafka.Error("b"); logger.Warn("c") },
+			"b,c",
+		},
+		{
+			"applied",
+			func() { _ = ApplyLevels(LevelConfig{Loggers: map[string]string{"kafka.producer": "debug"}}) },
+			func() { kafka.Debug("a"); kafka.Named("producer").Debug("b") },
+			"b",
+		},
+		{"cleared", ClearLoggerLevels, func() { kafka.Info("a"); kafka.Warn("b") }, "b"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start := len(RecentLogs())
+			tt.setup()
+			tt.log()
+
+			var messages []string
+			for _, entry := range RecentLogs()[start:] {
+				messages = append(messages, entry.Message)
+			}
+			if got := strings.Join(messages, ","); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"go.uber.org/zap/buffer"
+	"go.uber.org/zap/zapcore"
+)
+
+var logfmtPool = buffer.NewPool()
+
+// logfmtEncoder encodes entries with the JSON encoder, which keeps field
+// order and handles every field type, then rewrites the top level object as
+// key=value pairs. Nested objects and arrays are written as quoted JSON.
+type logfmtEncoder struct {
+	zapcore.Encoder
+}
+
+func newLogfmtEncoder(config zapcore.EncoderConfig) zapcore.Encoder {
+	config.LineEnding = "\n"
+	return logfmtEncoder{Encoder: zapcore.NewJSONEncoder(config)}
+}
+
+func (e logfmtEncoder) Clone() zapcore.Encoder {
+	return logfmtEncoder{Encoder: e.Encoder.Clone()}
+}
+
+func (e logfmtEncoder) EncodeEntry(entry zapcore.Entry, fields []zapcore.Field) (*buffer.Buffer, error) {
+	encoded, err := e.Encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return nil, err
+	}
+	defer encoded.Free()
+
+	decoder := json.NewDecoder(bytes.NewReader(encoded.Bytes()))
+	decoder.UseNumber()
+
+	if _, err := decoder.Token(); err != nil {
+		return nil, fmt.Errorf("failed to decode log entry: %w", err)
+	}
+
+	out := logfmtPool.Get()
+	for decoder.More() {
+		token, err := decoder.Token()
+		if err != nil {
+			out.Free()
+			return nil, fmt.Errorf("failed to decode log entry: %w", err)
+		}
+		key, _ := token.(string)
+
+		var raw json.RawMessage
+		if err := decoder.Decode(&raw); err != nil {
+			out.Free()
+			return nil, fmt.Errorf("failed to decode log entry: %w", err)
+		}
+
+		if out.Len() > 0 {
+			out.AppendByte(' ')
+		}
+		appendLogfmtKey(out, key)
+		out.AppendByte('=')
+		appendLogfmtValue(out, raw)
+	}
+	out.AppendByte('\n')
+
+	return out, nil
+}
+
+func appendLogfmtKey(out *buffer.Buffer, key string) {
+	for _, r := range key {
+		if r <= ' ' || r == '=' || r == '"' || r == unicode.ReplacementChar {
+			out.AppendByte('_')
+			continue
+		}
+		out.AppendString(string(r))
+	}
+}
+
+func appendLogfmtValue(out *buffer.Buffer, raw json.RawMessage) {
+	switch raw[0] {
+	case '"':
+		var value string
+		if err := json.Unmarshal(raw, &value); err == nil {
+			appendLogfmtString(out, value)
+			return
+		}
+	case '{', '[':
+		appendLogfmtString(out, string(raw))
+		return
+	}
+	out.AppendString(string(raw))
+}
+
+func appendLogfmtString(out *buffer.Buffer, value string) {
+	if value != "" && !strings.ContainsAny(value, " =\"\\\t\r\n") {
+		out.AppendString(value)
+		return
+	}
+	out.AppendString(strconv.Quote(value))
+}
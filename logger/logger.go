@@ -1,14 +1,15 @@
 package logger
 
 import (
+	"errors"
 	"os"
-	"strconv"
 
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
-var Logger *zap.Logger
+// Logger discards everything until InitLogger or InitDevLogger is called.
+var Logger = zap.NewNop()
 
 var encoderConfig = zapcore.EncoderConfig{
 	TimeKey:      "timestamp",
@@ -21,44 +22,60 @@ var encoderConfig = zapcore.EncoderConfig{
 	EncodeCaller: zapcore.ShortCallerEncoder,
 }
 
+// InitLogger replaces Logger with one built from the environment, see
+// ConfigFromEnv. Setup errors are logged through the new Logger.
 func InitLogger() {
-	var cores []zapcore.Core
+	initLogger(false)
+}
 
-	onConsoleEnv, exists := os.LookupEnv("LOG_ON_CONSOLE")
-	onConsole, _ := strconv.ParseBool(onConsoleEnv)
-	if !exists || onConsole {
-		cores = append(cores, consoleLoggerCore())
-	}
+// InitDevLogger sets up the same sinks as InitLogger, defaulting to the
+// console format and the debug level, in zap development mode.
+func InitDevLogger() {
+	initLogger(true)
+}
 
-	onFileEnv, exists := os.LookupEnv("LOG_ON_FILE")
-	onFile, _ := strconv.ParseBool(onFileEnv)
-	if exists && onFile {
-		cores = append(cores, fileLoggerCore())
+func initLogger(development bool) {
+	config, configErr := ConfigFromEnv()
+	if development {
+		config.Development = true
+		if !validEnvLevel("LOG_LEVEL") {
+			config.Level = zapcore.DebugLevel
+		}
 	}
 
-	onOpenSearchEnv, exists := os.LookupEnv("LOG_ON_OPEN_SEARCH")
-	onOpenSearch, _ := strconv.ParseBool(onOpenSearchEnv)
-	if exists && onOpenSearch {
-		createOpenSearchIndex(os.Getenv("OPEN_SEARCH_ENDPOINT"), os.Getenv("OPEN_SEARCH_INDEX_NAME"))
-		cores = append(cores, openSearchLoggercore())
+	logger, err := New(
+		WithConfig(config),
+		WithZapOptions(zap.AddCallerSkip(1)),
+	)
+	if installErr := Install(logger); installErr != nil {
+		err = errors.Join(err, installErr)
 	}
 
-	core := zapcore.NewTee(cores...)
+	for _, err := range append(unjoin(configErr), unjoin(err)...) {
+		Logger.Error(
+			"Error Initializing Logger",
+			zap.Error(err),
+		)
+	}
+}
 
-	Logger = zap.New(
-		core,
-		zap.AddCaller(),
-		zap.AddCallerSkip(1),
-		zap.AddStacktrace(zap.ErrorLevel),
-	)
+func validEnvLevel(name string) bool {
+	value, exists := os.LookupEnv(name)
+	if !exists {
+		return false
+	}
+	_, err := zapcore.ParseLevel(value)
+	return err == nil
 }
 
-func InitDevLogger() {
-	var err error
-	Logger, err = zap.NewDevelopment()
+func unjoin(err error) []error {
+	if joined, ok := err.(interface{ Unwrap() []error }); ok {
+		return joined.Unwrap()
+	}
 	if err != nil {
-		panic(err)
+		return []error{err}
 	}
+	return nil
 }
 
 func Sync() {
@@ -0,0 +1,192 @@
+// Package loggertest replaces the global logger.Logger during a test to
+// assert on what was logged:
+//
+//	logs := loggertest.New(t)
+//	_, err := etcd.AcquireLock(client, "orders/42", 10)
+//	logs.AssertLogged(t, loggertest.Error("Error Checking Lock Key"), loggertest.Field("lockKey", "orders/42"))
+//
+// Tests using it must not run in parallel with tests that log.
+package loggertest
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/DeltaNicola/infralib/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type Logs struct {
+	*observer.ObservedLogs
+}
+
+// New installs a logger recording every entry, from the debug level, as the
+// global logger.Logger and restores the previous one when the test ends.
+func New(t testing.TB) *Logs {
+	return NewAtLevel(t, zapcore.DebugLevel)
+}
+
+func NewAtLevel(t testing.TB, level zapcore.LevelEnabler) *Logs {
+	t.Helper()
+
+	core, observed := observer.New(level)
+
+	previous := logger.Logger
+	logger.Logger = zap.New(core, zap.AddCaller())
+	t.Cleanup(func() {
+		logger.Logger = previous
+	})
+
+	return &Logs{ObservedLogs: observed}
+}
+
+// Matcher matches a logged entry, its description is used in failure
+// messages.
+type Matcher struct {
+	description string
+	match       func(observer.LoggedEntry) bool
+}
+
+func (m Matcher) String() string {
+	return m.description
+}
+
+func NewMatcher(description string, match func(observer.LoggedEntry) bool) Matcher {
+	return Matcher{description: description, match: match}
+}
+
+func Level(level zapcore.Level) Matcher {
+	return NewMatcher("level="+level.String(), func(entry observer.LoggedEntry) bool {
+		return entry.Level == level
+	})
+}
+
+func Message(message string) Matcher {
+	return NewMatcher(fmt.Sprintf("message=%q", message), func(entry observer.LoggedEntry) bool {
+		return entry.Message == message
+	})
+}
+
+func MessageContains(substring string) Matcher {
+	return NewMatcher(fmt.Sprintf("message contains %q", substring), func(entry observer.LoggedEntry) bool {
+		return strings.Contains(entry.Message, substring)
+	})
+}
+
+func LoggerName(name string) Matcher {
+	return NewMatcher(fmt.Sprintf("logger=%q", name), func(entry observer.LoggedEntry) bool {
+		return entry.LoggerName == name
+	})
+}
+
+func Debug(message string) Matcher { return entryMatcher(zapcore.DebugLevel, message) }
+func Info(message string) Matcher  { return entryMatcher(zapcore.InfoLevel, message) }
+func Warn(message string) Matcher  { return entryMatcher(zapcore.WarnLevel, message) }
+func Error(message string) Matcher { return entryMatcher(zapcore.ErrorLevel, message) }
+
+func entryMatcher(level zapcore.Level, message string) Matcher {
+	return All(Level(level), Message(message))
+}
+
+// HasField matches entries with the field, whatever its value.
+func HasField(key string) Matcher {
+	return NewMatcher("has field "+key, func(entry observer.LoggedEntry) bool {
+		_, exists := entry.ContextMap()[key]
+		return exists
+	})
+}
+
+// Field matches entries with the field set to value. Values are compared as
+// logged, e.g. zap.Int fields as int64, falling back to their string form so
+// that Field("attempt", 3) matches zap.Int("attempt", 3).
+func Field(key string, value interface{}) Matcher {
+	return NewMatcher(fmt.Sprintf("%s=%v", key, value), func(entry observer.LoggedEntry) bool {
+		logged, exists := entry.ContextMap()[key]
+		if !exists {
+			return false
+		}
+		return reflect.DeepEqual(logged, value) || fmt.Sprint(logged) == fmt.Sprint(value)
+	})
+}
+
+// ErrorField matches entries whose "error" field contains substring.
+func ErrorField(substring string) Matcher {
+	return NewMatcher(fmt.Sprintf("error contains %q", substring), func(entry observer.LoggedEntry) bool {
+		logged, exists := entry.ContextMap()["error"]
+		return exists && strings.Contains(fmt.Sprint(logged), substring)
+	})
+}
+
+func All(matchers ...Matcher) Matcher {
+	descriptions := make([]string, len(matchers))
+	for i, matcher := range matchers {
+		descriptions[i] = matcher.description
+	}
+
+	return NewMatcher(strings.Join(descriptions, ", "), func(entry observer.LoggedEntry) bool {
+		for _, matcher := range matchers {
+			if !matcher.match(entry) {
+				return false
+			}
+		}
+		return true
+	})
+}
+
+// Find returns the entries matching every matcher.
+func (l *Logs) Find(matchers ...Matcher) []observer.LoggedEntry {
+	matcher := All(matchers...)
+
+	var found []observer.LoggedEntry
+	for _, entry := range l.All() {
+		if matcher.match(entry) {
+			found = append(found, entry)
+		}
+	}
+	return found
+}
+
+func (l *Logs) Contains(matchers ...Matcher) bool {
+	return len(l.Find(matchers...)) > 0
+}
+
+func (l *Logs) AssertLogged(t testing.TB, matchers ...Matcher) {
+	t.Helper()
+
+	if !l.Contains(matchers...) {
+		t.Errorf("no entry logged with %s, logged:\n%s", All(matchers...), l.dump())
+	}
+}
+
+func (l *Logs) AssertNotLogged(t testing.TB, matchers ...Matcher) {
+	t.Helper()
+
+	if found := l.Find(matchers...); len(found) > 0 {
+		t.Errorf("%d entries logged with %s, logged:\n%s", len(found), All(matchers...), l.dump())
+	}
+}
+
+func (l *Logs) AssertCount(t testing.TB, count int, matchers ...Matcher) {
+	t.Helper()
+
+	if found := l.Find(matchers...); len(found) != count {
+		t.Errorf("%d entries logged with %s instead of %d, logged:\n%s", len(found), All(matchers...), count, l.dump())
+	}
+}
+
+func (l *Logs) dump() string {
+	entries := l.All()
+	if len(entries) == 0 {
+		return "  (nothing)"
+	}
+
+	var b strings.Builder
+	for _, entry := range entries {
+		fmt.Fprintf(&b, "  %s %q %v\n", entry.Level.CapitalString(), entry.Message, entry.ContextMap())
+	}
+	return b.String()
+}
@@ -0,0 +1,133 @@
+package loggertest
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/DeltaNicola/infralib/logger"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// recorder collects the failures of the assertions under test.
+type recorder struct {
+	testing.TB
+	failures []string
+}
+
+func (r *recorder) Helper() {}
+
+func (r *recorder) Errorf(format string, args ...interface{}) {
+	r.failures = append(r.failures, fmt.Sprintf(format, args...))
+}
+
+func TestMatchers(t *testing.T) {
+	logs := New(t)
+	logger.Logger.Named("kafka").Error(
+		"Error Sending to Topic",
+		zap.String("topic", "orders"),
+		zap.Int("attempt", 3),
+		zap.Error(errors.New("broker down")),
+	)
+
+	tests := []struct {
+		name    string
+		matcher Matcher
+		match   bool
+	}{
+		{"level", Level(zapcore.ErrorLevel), true},
+		{"other level", Level(zapcore.WarnLevel), false},
+		{"message", Message("Error Sending to Topic"), true},
+		{"partial message", Message("Error Sending"), false},
+		{"message contains", MessageContains("Sending"), true},
+		{"logger name", LoggerName("kafka"), true},
+		{"other logger name", LoggerName("etcd"), false},
+		{"error", Error("Error Sending to Topic"), true},
+		{"warn", Warn("Error Sending to Topic"), false},
+		{"has field", HasField("topic"), true},
+		{"missing field", HasField("partition"), false},
+		{"string field", Field("topic", "orders"), true},
+		{"int field", Field("attempt", 3), true},
+		{"int64 field", Field("attempt", int64(3)), true},
+		{"other field value", Field("attempt", 4), false},
+		{"error field", ErrorField("broker"), true},
+		{"other error field", ErrorField("timeout"), false},
+		{"all", All(Error("Error Sending to Topic"), Field("topic", "orders")), true},
+		{"all with a mismatch", All(Error("Error Sending to Topic"), Field("topic", "payments")), false},
+		{"custom", NewMatcher("has caller", func(entry observer.LoggedEntry) bool { return entry.Caller.Defined }), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := logs.Contains(tt.matcher); got != tt.match {
+				t.Errorf("%s matched %v, want %v", tt.matcher, got, tt.match)
+			}
+		})
+	}
+}
+
+func TestAssertions(t *testing.T) {
+	logs := New(t)
+	logger.Logger.Info("Lock Acquired", zap.String("lockKey", "orders/42"))
+	logger.Logger.Info("Lock Acquired", zap.String("lockKey", "orders/43"))
+
+	tests := []struct {
+		name    string
+		assert  func(testing.TB)
+		failure string
+	}{
+		{"logged", func(tb testing.TB) { logs.AssertLogged(tb, Info("Lock Acquired")) }, ""},
+		{
+			"not logged",
+			func(tb testing.TB) { logs.AssertLogged(tb, Info("Lock Released")) },
+			`no entry logged with level=info, message="Lock Released"`,
+		},
+		{"absent", func(tb testing.TB) { logs.AssertNotLogged(tb, Error("Lock Acquired")) }, ""},
+		{
+			"present",
+			func(tb testing.TB) { logs.AssertNotLogged(tb, Field("lockKey", "orders/42")) },
+			"1 entries logged with lockKey=orders/42",
+		},
+		{"count", func(tb testing.TB) { logs.AssertCount(tb, 2, Info("Lock Acquired")) }, ""},
+		{
+			"wrong count",
+			func(tb testing.TB) { logs.AssertCount(tb, 1, Info("Lock Acquired")) },
+			`2 entries logged with level=info, message="Lock Acquired" instead of 1`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := &recorder{TB: t}
+			tt.assert(r)
+
+			switch {
+			case tt.failure == "" && len(r.failures) > 0:
+				t.Errorf("got failures %q", r.failures)
+			case tt.failure != "" && (len(r.failures) != 1 || !strings.HasPrefix(r.failures[0], tt.failure)):
+				t.Errorf("got failures %q, want %q", r.failures, tt.failure)
+			}
+		})
+	}
+}
+
+func TestNewRestoresLogger(t *testing.T) {
+	previous := logger.Logger
+
+	t.Run("replaced", func(t *testing.T) {
+		logs := NewAtLevel(t, zapcore.WarnLevel)
+		logger.Logger.Info("Ignored")
+		logger.Logger.Warn("Recorded")
+
+		if logs.Len() != 1 || logs.All()[0].Message != "Recorded" {
+			t.Errorf("got %d entries, want only the warning", logs.Len())
+		}
+	})
+
+	if logger.Logger != previous {
+		t.Error("the previous logger was not restored")
+	}
+}
@@ -0,0 +1,293 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang/snappy"
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+var errLokiRejected = errors.New("logs rejected by Loki")
+
+type LokiConfig struct {
+	// Endpoint is the Loki base URL, the push path is added when missing.
+	Endpoint string
+	// Labels are the stream labels of every entry, e.g. service and env.
+	Labels map[string]string
+	// LevelLabel adds the level of the entry as a "level" label.
+	LevelLabel bool
+	Batch      BatchConfig
+	MaxRetries int
+	// MaxRetryWait bounds the time spent waiting between the retries of a
+	// push, while the next batches wait in the queue.
+	MaxRetryWait time.Duration
+
+	TenantID    string // Sent as X-Scope-OrgID
+	Username    string
+	Password    string
+	BearerToken string
+	TLS         TLSConfig
+}
+
+func lokiConfigFromEnv() LokiConfig {
+	labels := map[string]string{}
+	if service := os.Getenv("SERVICE_NAME"); service != "" {
+		labels["service"] = service
+	}
+	if env := os.Getenv("ENVIRONMENT"); env != "" {
+		labels["env"] = env
+	}
+	for _, pair := range strings.Split(os.Getenv("LOKI_LABELS"), ",") {
+		if name, value, ok := strings.Cut(pair, "="); ok && strings.TrimSpace(name) != "" {
+			labels[strings.TrimSpace(name)] = strings.TrimSpace(value)
+		}
+	}
+
+	return LokiConfig{
+		Endpoint:     strings.TrimSuffix(os.Getenv("LOKI_ENDPOINT"), "/"),
+		Labels:       labels,
+		LevelLabel:   envBool("LOKI_LEVEL_LABEL", true),
+		Batch:        batchConfigFromEnv("LOKI"),
+		MaxRetries:   envInt("LOKI_MAX_RETRIES", 5),
+		MaxRetryWait: envDuration("LOKI_MAX_RETRY_WAIT", 10*time.Second),
+
+		TenantID:    os.Getenv("LOKI_TENANT_ID"),
+		Username:    os.Getenv("LOKI_USERNAME"),
+		Password:    os.Getenv("LOKI_PASSWORD"),
+		BearerToken: os.Getenv("LOKI_BEARER_TOKEN"),
+		TLS:         tlsConfigFromEnv("LOKI"),
+	}
+}
+
+func (i *instance) lokiLoggerCore(config LokiConfig) (zapcore.Core, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("LOKI_ENDPOINT is not set")
+	}
+	if !strings.HasSuffix(config.Endpoint, "/loki/api/v1/push") {
+		config.Endpoint += "/loki/api/v1/push"
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.TLS.enabled() {
+		tlsConfig, err := config.TLS.build()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	pusher := &lokiPusher{
+		config: config,
+		client: &http.Client{
+			Timeout:   10 * time.Second,
+			Transport: transport,
+		},
+		streams: map[zapcore.Level]string{},
+	}
+	pusher.batcher = newBatcher(SinkLoki, config.Batch, pusher.push)
+	pusher.batcher.onError = i.sinkErrorReporter(SinkLoki)
+	pusher.batcher.start()
+	i.onClose(func() error {
+		err := pusher.batcher.close()
+		pusher.client.CloseIdleConnections()
+		return err
+	})
+
+	encoder, err := i.sinkEncoder(SinkLoki)
+	return i.wrapSinkCore(SinkLoki, &lokiCore{encoder: encoder, pusher: pusher}), err
+}
+
+type lokiEntry struct {
+	labels string
+	time   time.Time
+	line   string
+}
+
+type lokiCore struct {
+	encoder zapcore.Encoder
+	pusher  *lokiPusher
+}
+
+func (c *lokiCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *lokiCore) With(fields []zapcore.Field) zapcore.Core {
+	encoder := c.encoder.Clone()
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+	return &lokiCore{encoder: encoder, pusher: c.pusher}
+}
+
+func (c *lokiCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checked.AddCore(entry, c)
+}
+
+func (c *lokiCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	err = c.pusher.batcher.add(lokiEntry{
+		labels: c.pusher.labels(entry.Level),
+		time:   entry.Time,
+		line:   strings.TrimRight(buf.String(), "\n"),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to queue log for Loki: %w", err)
+	}
+
+	if entry.Level > zapcore.ErrorLevel {
+		// The process is about to panic or exit
+		return c.Sync()
+	}
+	return nil
+}
+
+func (c *lokiCore) Sync() error {
+	return c.pusher.batcher.sync()
+}
+
+type lokiPusher struct {
+	config  LokiConfig
+	client  *http.Client
+	batcher *batcher[lokiEntry]
+
+	mu      sync.Mutex
+	streams map[zapcore.Level]string
+}
+
+// labels returns the stream selector of the level, e.g.
+// {env="prod", level="error", service="billing"}.
+func (p *lokiPusher) labels(level zapcore.Level) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if labels, exists := p.streams[level]; exists {
+		return labels
+	}
+
+	values := make(map[string]string, len(p.config.Labels)+1)
+	for name, value := range p.config.Labels {
+		values[name] = value
+	}
+	if p.config.LevelLabel {
+		values["level"] = level.String()
+	}
+
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = name + "=" + strconv.Quote(values[name])
+	}
+	labels := "{" + strings.Join(pairs, ", ") + "}"
+
+	p.streams[level] = labels
+	return labels
+}
+
+func (p *lokiPusher) push(entries []lokiEntry) error {
+	body := snappy.Encode(nil, encodeLokiPushRequest(entries))
+
+	err := retry(p.config.MaxRetries, p.config.MaxRetryWait, errLokiRejected, func() (time.Duration, error) {
+		return p.send(body)
+	}, func() {
+		p.batcher.stats.retried.Add(uint64(len(entries)))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to push %d logs to Loki: %w", len(entries), err)
+	}
+
+	return nil
+}
+
+func (p *lokiPusher) send(body []byte) (time.Duration, error) {
+	req, err := http.NewRequest("POST", p.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-protobuf")
+	if p.config.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", p.config.TenantID)
+	}
+	switch {
+	case p.config.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+p.config.BearerToken)
+	case p.config.Username != "":
+		req.SetBasicAuth(p.config.Username, p.config.Password)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return 0, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return retryAfter(resp), fmt.Errorf("received unexpected status code from Loki: %d", resp.StatusCode)
+	default:
+		return 0, fmt.Errorf("%w: status code %d", errLokiRejected, resp.StatusCode)
+	}
+}
+
+// encodeLokiPushRequest encodes the logproto.PushRequest message:
+//
+//	PushRequest   { repeated StreamAdapter streams = 1; }
+//	StreamAdapter { string labels = 1; repeated EntryAdapter entries = 2; }
+//	EntryAdapter  { google.protobuf.Timestamp timestamp = 1; string line = 2; }
+func encodeLokiPushRequest(entries []lokiEntry) []byte {
+	var order []string
+	streams := map[string][]byte{}
+	for _, entry := range entries {
+		stream, exists := streams[entry.labels]
+		if !exists {
+			order = append(order, entry.labels)
+			stream = protowire.AppendTag(stream, 1, protowire.BytesType)
+			stream = protowire.AppendString(stream, entry.labels)
+		}
+
+		var timestamp []byte
+		timestamp = protowire.AppendTag(timestamp, 1, protowire.VarintType)
+		timestamp = protowire.AppendVarint(timestamp, uint64(entry.time.Unix()))
+		timestamp = protowire.AppendTag(timestamp, 2, protowire.VarintType)
+		timestamp = protowire.AppendVarint(timestamp, uint64(entry.time.Nanosecond()))
+
+		var message []byte
+		message = protowire.AppendTag(message, 1, protowire.BytesType)
+		message = protowire.AppendBytes(message, timestamp)
+		message = protowire.AppendTag(message, 2, protowire.BytesType)
+		message = protowire.AppendString(message, entry.line)
+
+		stream = protowire.AppendTag(stream, 2, protowire.BytesType)
+		streams[entry.labels] = protowire.AppendBytes(stream, message)
+	}
+
+	var request []byte
+	for _, labels := range order {
+		request = protowire.AppendTag(request, 1, protowire.BytesType)
+		request = protowire.AppendBytes(request, streams[labels])
+	}
+	return request
+}
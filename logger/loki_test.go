@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+type decodedLokiStream struct {
+	labels string
+	lines  []string
+}
+
+// decodeLokiPushRequest decodes a PushRequest into its streams, the entries
+// are rendered as "<seconds>.<nanos> <line>".
+func decodeLokiPushRequest(t *testing.T, data []byte) []decodedLokiStream {
+	t.Helper()
+
+	var streams []decodedLokiStream
+	forEachField(t, data, func(number protowire.Number, value []byte) {
+		if number != 1 {
+			t.Fatalf("unexpected PushRequest field %d", number)
+		}
+
+		var stream decodedLokiStream
+		forEachField(t, value, func(number protowire.Number, value []byte) {
+			switch number {
+			case 1:
+				stream.labels = string(value)
+			case 2:
+				var seconds, nanos uint64
+				var line string
+				forEachField(t, value, func(number protowire.Number, value []byte) {
+					switch number {
+					case 1:
+						forEachVarint(t, value, func(number protowire.Number, v uint64) {
+							if number == 1 {
+								seconds = v
+							} else {
+								nanos = v
+							}
+						})
+					case 2:
+						line = string(value)
+					}
+				})
+				stream.lines = append(stream.lines, fmt.Sprintf("%d.%09d %s", seconds, nanos, line))
+			}
+		})
+		streams = append(streams, stream)
+	})
+	return streams
+}
+
+func forEachField(t *testing.T, data []byte, f func(protowire.Number, []byte)) {
+	t.Helper()
+
+	for len(data) > 0 {
+		number, typ, n := protowire.ConsumeTag(data)
+		if n < 0 || typ != protowire.BytesType {
+			t.Fatalf("invalid tag: %v", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		value, n := protowire.ConsumeBytes(data)
+		if n < 0 {
+			t.Fatalf("invalid field %d: %v", number, protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		f(number, value)
+	}
+}
+
+func forEachVarint(t *testing.T, data []byte, f func(protowire.Number, uint64)) {
+	t.Helper()
+
+	for len(data) > 0 {
+		number, typ, n := protowire.ConsumeTag(data)
+		if n < 0 || typ != protowire.VarintType {
+			t.Fatalf("invalid tag: %v", protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		value, n := protowire.ConsumeVarint(data)
+		if n < 0 {
+			t.Fatalf("invalid field %d: %v", number, protowire.ParseError(n))
+		}
+		data = data[n:]
+
+		f(number, value)
+	}
+}
+
+func TestEncodeLokiPushRequest(t *testing.T) {
+	at := time.Unix(1714566600, 123456789)
+
+	tests := []struct {
+		name    string
+		entries []lokiEntry
+		want    []decodedLokiStream
+	}{
+		{
+			name: "empty",
+		},
+		{
+			name:    "one entry",
+			entries: []lokiEntry{{labels: `{level="info"}`, time: at, line: "started"}},
+			want:    []decodedLokiStream{{labels: `{level="info"}`, lines: []string{"1714566600.123456789 started"}}},
+		},
+		{
+			name: "streams in order of first entry",
+			entries: []lokiEntry{
+				{labels: `{level="warn"}`, time: at, line: "slow"},
+				{labels: `{level="info"}`, time: at.Add(time.Second), line: "ok"},
+				{labels: `{level="warn"}`, time: at.Add(2 * time.Second), line: "slower"},
+			},
+			want: []decodedLokiStream{
+				{labels: `{level="warn"}`, lines: []string{"1714566600.123456789 slow", "1714566602.123456789 slower"}},
+				{labels: `{level="info"}`, lines: []string{"1714566601.123456789 ok"}},
+			},
+		},
+		{
+			name:    "unicode line",
+			entries: []lokiEntry{{labels: `{}`, time: time.Unix(0, 0), line: "caffè ☕"}},
+			want:    []decodedLokiStream{{labels: `{}`, lines: []string{"0.000000000 caffè ☕"}}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := decodeLokiPushRequest(t, encodeLokiPushRequest(tt.entries))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLokiLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		config LokiConfig
+		level  zapcore.Level
+		want   string
+	}{
+		{"no labels", LokiConfig{}, zapcore.InfoLevel, `{}`},
+		{"level", LokiConfig{LevelLabel: true}, zapcore.ErrorLevel, `{level="error"}`},
+		{
+			"sorted",
+			LokiConfig{Labels: map[string]string{"service": "billing", "env": "prod"}, LevelLabel: true},
+			zapcore.WarnLevel,
+			`{env="prod", level="warn", service="billing"}`,
+		},
+		{"quoted", LokiConfig{Labels: map[string]string{"team": `a"b`}}, zapcore.InfoLevel, `{team="a\"b"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pusher := &lokiPusher{config: tt.config, streams: map[zapcore.Level]string{}}
+			if got := pusher.labels(tt.level); got != tt.want {
+				t.Errorf("got %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,337 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+type MemoryEntry struct {
+	Time    time.Time              `json:"timestamp"`
+	Level   zapcore.Level          `json:"level"`
+	Logger  string                 `json:"logger,omitempty"`
+	Caller  string                 `json:"caller,omitempty"`
+	Message string                 `json:"message"`
+	Fields  map[string]interface{} `json:"fields,omitempty"`
+	Stack   string                 `json:"stacktrace,omitempty"`
+}
+
+func (e MemoryEntry) String() string {
+	var b strings.Builder
+	b.WriteString(e.Time.Format("2006-01-02T15:04:05.000Z0700"))
+	b.WriteString("\t" + e.Level.CapitalString())
+	if e.Logger != "" {
+		b.WriteString("\t" + e.Logger)
+	}
+	if e.Caller != "" {
+		b.WriteString("\t" + e.Caller)
+	}
+	b.WriteString("\t" + e.Message)
+	if len(e.Fields) > 0 {
+		if fields, err := json.Marshal(e.Fields); err == nil {
+			b.WriteString("\t" + string(fields))
+		}
+	}
+	if e.Stack != "" {
+		b.WriteString("\n" + e.Stack)
+	}
+	return b.String()
+}
+
+type ringBuffer struct {
+	mu          sync.Mutex
+	entries     []MemoryEntry
+	next        int
+	full        bool
+	subscribers map[chan MemoryEntry]struct{}
+}
+
+func newRingBuffer(size int) *ringBuffer {
+	if size <= 0 {
+		size = 1000
+	}
+	return &ringBuffer{
+		entries:     make([]MemoryEntry, size),
+		subscribers: map[chan MemoryEntry]struct{}{},
+	}
+}
+
+func (b *ringBuffer) add(entry MemoryEntry) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.entries[b.next] = entry
+	b.next = (b.next + 1) % len(b.entries)
+	if b.next == 0 {
+		b.full = true
+	}
+
+	for subscriber := range b.subscribers {
+		select {
+		case subscriber <- entry:
+		default: // Slow readers miss entries rather than block logging
+		}
+	}
+}
+
+// snapshot returns the entries from the oldest to the newest.
+func (b *ringBuffer) snapshot() []MemoryEntry {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.ordered()
+}
+
+// ordered must be called with mu held.
+func (b *ringBuffer) ordered() []MemoryEntry {
+	if !b.full {
+		return append([]MemoryEntry(nil), b.entries[:b.next]...)
+	}
+	return append(append([]MemoryEntry(nil), b.entries[b.next:]...), b.entries[:b.next]...)
+}
+
+// subscribe returns the current entries and a channel receiving the new ones,
+// so that none is missed or repeated in between.
+func (b *ringBuffer) subscribe() ([]MemoryEntry, chan MemoryEntry, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	ch := make(chan MemoryEntry, 100)
+	b.subscribers[ch] = struct{}{}
+
+	return b.ordered(), ch, func() {
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		b.mu.Unlock()
+	}
+}
+
+func (i *instance) memoryLoggerCore(size int) zapcore.Core {
+	i.memory = newRingBuffer(size)
+
+	return i.wrapSinkCore(SinkMemory, &memoryCore{buffer: i.memory})
+}
+
+type memoryCore struct {
+	buffer *ringBuffer
+	fields []zapcore.Field
+}
+
+func (c *memoryCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *memoryCore) With(fields []zapcore.Field) zapcore.Core {
+	return &memoryCore{
+		buffer: c.buffer,
+		fields: append(c.fields[:len(c.fields):len(c.fields)], fields...),
+	}
+}
+
+func (c *memoryCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checked.AddCore(entry, c)
+}
+
+func (c *memoryCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range c.fields {
+		field.AddTo(encoder)
+	}
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+
+	memoryEntry := MemoryEntry{
+		Time:    entry.Time,
+		Level:   entry.Level,
+		Logger:  entry.LoggerName,
+		Message: entry.Message,
+		Fields:  encoder.Fields,
+		Stack:   entry.Stack,
+	}
+	if entry.Caller.Defined {
+		memoryEntry.Caller = entry.Caller.TrimmedPath()
+	}
+
+	c.buffer.add(memoryEntry)
+	return nil
+}
+
+func (c *memoryCore) Sync() error {
+	return nil
+}
+
+// RecentLogs returns the entries kept by the memory sink of the installed
+// logger, oldest first.
+func RecentLogs() []MemoryEntry {
+	buffer := current().memory
+	if buffer == nil {
+		return nil
+	}
+	return buffer.snapshot()
+}
+
+type memoryFilter struct {
+	level zapcore.Level
+	query string
+	since time.Time
+}
+
+func (f memoryFilter) match(entry MemoryEntry) bool {
+	return entry.Level >= f.level &&
+		!entry.Time.Before(f.since) &&
+		(f.query == "" || strings.Contains(strings.ToLower(entry.Message), f.query))
+}
+
+// MemoryHandler serves the entries kept by the memory sink of the installed
+// logger, as JSON or, with format=text, as plain text lines. They can be
+// filtered with level (the minimum level), q (a case insensitive message
+// substring), since (a timestamp or a duration like 5m) and limit (the newest
+// N entries). With follow=true, or Accept: text/event-stream, new entries are
+// streamed as server-sent events.
+func MemoryHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet {
+			w.Header().Set("Allow", "GET")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		buffer := current().memory
+		if buffer == nil {
+			http.Error(w, "memory log sink is not enabled", http.StatusNotFound)
+			return
+		}
+
+		query := r.URL.Query()
+		filter := memoryFilter{
+			level: zapcore.DebugLevel,
+			query: strings.ToLower(query.Get("q")),
+		}
+		if value := query.Get("level"); value != "" {
+			level, err := zapcore.ParseLevel(value)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			filter.level = level
+		}
+		if value := query.Get("since"); value != "" {
+			since, err := parseSince(value)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			filter.since = since
+		}
+		limit := 0
+		if value := query.Get("limit"); value != "" {
+			var err error
+			if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+				http.Error(w, fmt.Sprintf("invalid limit %q", value), http.StatusBadRequest)
+				return
+			}
+		}
+
+		text := query.Get("format") == "text"
+		if query.Get("follow") == "true" || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+			tailLogs(w, r, buffer, filter, limit, text)
+			return
+		}
+
+		entries := filterEntries(buffer.snapshot(), filter, limit)
+
+		if text {
+			w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+			for _, entry := range entries {
+				fmt.Fprintln(w, entry.String())
+			}
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(entries)
+	})
+}
+
+func parseSince(value string) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid since %q, expected a duration or an RFC 3339 timestamp", value)
+	}
+	return since, nil
+}
+
+func filterEntries(entries []MemoryEntry, filter memoryFilter, limit int) []MemoryEntry {
+	filtered := make([]MemoryEntry, 0, len(entries))
+	for _, entry := range entries {
+		if filter.match(entry) {
+			filtered = append(filtered, entry)
+		}
+	}
+	if limit > 0 && len(filtered) > limit {
+		filtered = filtered[len(filtered)-limit:]
+	}
+	return filtered
+}
+
+func tailLogs(w http.ResponseWriter, r *http.Request, buffer *ringBuffer, filter memoryFilter, limit int, text bool) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	entries, ch, unsubscribe := buffer.subscribe()
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	send := func(entry MemoryEntry) {
+		var data string
+		if text {
+			data = strings.ReplaceAll(entry.String(), "\n", "\ndata: ")
+		} else {
+			encoded, err := json.Marshal(entry)
+			if err != nil {
+				return
+			}
+			data = string(encoded)
+		}
+		fmt.Fprintf(w, "data: %s\n\n", data)
+	}
+
+	for _, entry := range filterEntries(entries, filter, limit) {
+		send(entry)
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case entry := <-ch:
+			if filter.match(entry) {
+				send(entry)
+				flusher.Flush()
+			}
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": keep-alive\n\n")
+			flusher.Flush()
+		}
+	}
+}
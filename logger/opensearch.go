@@ -2,56 +2,450 @@ package logger
 
 import (
 	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
+	"net/url"
 	"os"
+	"strings"
+	"sync"
 	"time"
 
-	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
 )
 
+var errOpenSearchRejected = errors.New("logs rejected by OpenSearch")
+
+type OpenSearchConfig struct {
+	Endpoint  string
+	Index     string
+	Format    string // FormatJSON or FormatECS
+	Rollover  string // RolloverNone, RolloverDaily or RolloverWeekly
+	Shards    int
+	Replicas  int
+	Retention string // ISM min_index_age before deletion, e.g. "30d", needs a Rollover; empty disables the policy
+	Batch     BatchConfig
+	Spool     SpoolConfig
+	// MaxRetries and MaxRetryWait bound the retries of the logs that failed
+	// with 429 or a 5xx status, when the spool is disabled.
+	MaxRetries   int
+	MaxRetryWait time.Duration
+
+	Username    string
+	Password    string
+	APIKey      string
+	BearerToken string
+	TLS         TLSConfig
+	TLSConfig   *tls.Config // Takes precedence over TLS when set
+}
+
+func openSearchConfigFromEnv() OpenSearchConfig {
+	return OpenSearchConfig{
+		Endpoint:  strings.TrimSuffix(os.Getenv("OPEN_SEARCH_ENDPOINT"), "/"),
+		Index:     os.Getenv("OPEN_SEARCH_INDEX_NAME"),
+		Rollover:  strings.ToLower(os.Getenv("OPEN_SEARCH_INDEX_ROLLOVER")),
+		Shards:    envInt("OPEN_SEARCH_INDEX_SHARDS", 1),
+		Replicas:  envInt("OPEN_SEARCH_INDEX_REPLICAS", 0),
+		Retention: os.Getenv("OPEN_SEARCH_INDEX_RETENTION"),
+		Batch:     batchConfigFromEnv("OPEN_SEARCH"),
+		Spool:     spoolConfigFromEnv(),
+
+		MaxRetries:   envInt("OPEN_SEARCH_MAX_RETRIES", 3),
+		MaxRetryWait: envDuration("OPEN_SEARCH_MAX_RETRY_WAIT", 10*time.Second),
+
+		Username:    os.Getenv("OPEN_SEARCH_USERNAME"),
+		Password:    os.Getenv("OPEN_SEARCH_PASSWORD"),
+		APIKey:      os.Getenv("OPEN_SEARCH_API_KEY"),
+		BearerToken: os.Getenv("OPEN_SEARCH_BEARER_TOKEN"),
+		TLS:         tlsConfigFromEnv("OPEN_SEARCH"),
+	}
+}
+
 type OpenSearchWriter struct {
-	endpoint string
-	client   *http.Client
+	config  OpenSearchConfig
+	client  *http.Client
+	batcher *batcher[[]byte]
+	spool   *spool
+
+	indexMu    sync.Mutex
+	writeIndex string
 }
 
+// NewOpenSearchWriter returns a writer sending the logs to an index URL like
+// http://localhost:9200/logs/_doc, in batches through the _bulk API.
+//
+// Deprecated: use NewOpenSearchWriterWithConfig, which also sets up
+// authentication, TLS, rolling indices and the spool.
 func NewOpenSearchWriter(endpoint string) *OpenSearchWriter {
-	return &OpenSearchWriter{
-		endpoint: endpoint,
-		client: &http.Client{
-			Timeout: 5 * time.Second,
-		},
+	config := OpenSearchConfig{Endpoint: strings.TrimSuffix(endpoint, "/")}
+	if u, err := url.Parse(endpoint); err == nil {
+		segments := strings.Split(strings.Trim(u.Path, "/"), "/")
+		if segments[len(segments)-1] == "_doc" {
+			segments = segments[:len(segments)-1]
+		}
+		config.Index = segments[len(segments)-1]
+		u.Path = strings.Join(segments[:len(segments)-1], "/")
+		config.Endpoint = strings.TrimSuffix(u.String(), "/")
+	}
+
+	// Only TLS, spool, rollover and format settings can fail, none is set
+	writer, _ := NewOpenSearchWriterWithConfig(config)
+	return writer
+}
+
+// NewOpenSearchWriterWithConfig returns a writer sending the logs to
+// config.Index in batches. Delivery errors are reported on the error output
+// of the installed logger.
+func NewOpenSearchWriterWithConfig(config OpenSearchConfig) (*OpenSearchWriter, error) {
+	return newOpenSearchWriter(config, func(err error) {
+		reportSinkError(SinkOpenSearch, err)
+	})
+}
+
+func newOpenSearchWriter(config OpenSearchConfig, onError func(error)) (*OpenSearchWriter, error) {
+	switch config.Rollover {
+	case RolloverNone, RolloverDaily, RolloverWeekly:
+	default:
+		return nil, fmt.Errorf("unknown OpenSearch index rollover %q", config.Rollover)
+	}
+	if config.Retention != "" && config.Rollover == RolloverNone {
+		// The policy would delete the only index with every log in it
+		return nil, errors.New("OpenSearch index retention needs a daily or weekly rollover")
+	}
+
+	switch config.Format {
+	case "":
+		config.Format = FormatJSON
+	case FormatJSON, FormatECS:
+	default:
+		return nil, fmt.Errorf("unsupported OpenSearch log format %q", config.Format)
+	}
+
+	client, err := newOpenSearchClient(config)
+	if err != nil {
+		return nil, err
+	}
+
+	w := &OpenSearchWriter{
+		config: config,
+		client: client,
+	}
+
+	if config.Spool.Enabled {
+		spool, err := newSpool(config.Spool)
+		if err != nil {
+			return nil, err
+		}
+		w.spool = spool
+	}
+
+	w.batcher = newBatcher(SinkOpenSearch, config.Batch, w.flush)
+	w.batcher.onError = onError
+	if w.spool != nil {
+		w.batcher.onTick = func() error {
+			_, err := w.replay()
+			return err
+		}
+	}
+	w.batcher.start()
+
+	return w, nil
+}
+
+func newOpenSearchClient(config OpenSearchConfig) (*http.Client, error) {
+	tlsConfig := config.TLSConfig
+	if tlsConfig == nil && config.TLS.enabled() {
+		var err error
+		if tlsConfig, err = config.TLS.build(); err != nil {
+			return nil, err
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if tlsConfig != nil {
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	return &http.Client{
+		Timeout:   5 * time.Second,
+		Transport: transport,
+	}, nil
+}
+
+func (w *OpenSearchWriter) authorize(req *http.Request) {
+	switch {
+	case w.config.APIKey != "":
+		req.Header.Set("Authorization", "ApiKey "+w.config.APIKey)
+	case w.config.BearerToken != "":
+		req.Header.Set("Authorization", "Bearer "+w.config.BearerToken)
+	case w.config.Username != "":
+		req.SetBasicAuth(w.config.Username, w.config.Password)
 	}
 }
 
 func (w *OpenSearchWriter) Write(p []byte) (n int, err error) {
-	req, err := http.NewRequest("POST", w.endpoint, bytes.NewBuffer(p))
+	entry := make([]byte, len(p))
+	copy(entry, p)
+
+	if err := w.batcher.add(entry); err != nil {
+		return 0, fmt.Errorf("failed to queue log for OpenSearch: %w", err)
+	}
+
+	return len(p), nil
+}
+
+func (w *OpenSearchWriter) Sync() error {
+	return w.batcher.sync()
+}
+
+func (w *OpenSearchWriter) Close() error {
+	err := w.batcher.close()
+	w.client.CloseIdleConnections()
+	return err
+}
+
+// spoolReplayBatches bounds the spooled batches sent per flush or tick, so
+// that a large backlog does not hold up the batcher.
+const spoolReplayBatches = 10
+
+func (w *OpenSearchWriter) flush(entries [][]byte) error {
+	if w.spool != nil {
+		// Spooled batches are older than this one: keep them in front of it
+		// until they have all been delivered.
+		remaining, err := w.replay()
+		if err != nil || remaining > 0 {
+			if spoolErr := w.spool.store(entries); spoolErr != nil {
+				return errors.Join(err, spoolErr)
+			}
+			if err != nil {
+				err = fmt.Errorf("%w, %d logs: %w", errSpooled, len(entries), err)
+			}
+			return &partialFlush{retried: len(entries), err: err}
+		}
+	}
+
+	// With a spool the entries are retried from there rather than here.
+	maxRetries := w.config.MaxRetries
+	if w.spool != nil {
+		maxRetries = 0
+	}
+
+	pending := entries
+	var rejected int
+	var rejectedErr error
+	err := retry(maxRetries, w.config.MaxRetryWait, errOpenSearchRejected, func() (time.Duration, error) {
+		retryable, failed, err := w.deliver(pending)
+		if failed > 0 {
+			rejected += failed
+			rejectedErr = err
+		}
+		pending = retryable
+		if len(pending) == 0 {
+			return 0, nil
+		}
+		return 0, err
+	}, func() {
+		w.batcher.stats.retried.Add(uint64(len(pending)))
+	})
+
+	var spooled int
+	if len(pending) > 0 && w.spool != nil {
+		if spoolErr := w.spool.store(pending); spoolErr != nil {
+			err = errors.Join(err, spoolErr)
+		} else {
+			spooled = len(pending)
+			err = fmt.Errorf("%w, %d logs: %w", errSpooled, spooled, err)
+			pending = nil
+		}
+	}
+
+	failed := rejected + len(pending)
+	if failed == 0 && spooled == 0 {
+		return nil
+	}
+	if err == nil {
+		err = rejectedErr
+	}
+	return &partialFlush{
+		written: len(entries) - failed - spooled,
+		retried: spooled,
+		failed:  failed,
+		err:     err,
+	}
+}
+
+// deliver sends entries with a bulk request and returns the ones that can be
+// sent again and the number of the ones OpenSearch rejected.
+func (w *OpenSearchWriter) deliver(entries [][]byte) ([][]byte, int, error) {
+	err := w.bulk(entries)
+
+	var items *bulkItemsError
+	switch {
+	case err == nil:
+		return nil, 0, nil
+	case errors.As(err, &items):
+		return items.retryable, items.rejected, err
+	case errors.Is(err, errOpenSearchRejected):
+		return nil, len(entries), err
+	default:
+		return entries, 0, err
+	}
+}
+
+// replay sends the oldest spooled batches and returns the number of batches
+// still spooled.
+func (w *OpenSearchWriter) replay() (int, error) {
+	return w.spool.replay(func(entries [][]byte) ([][]byte, error) {
+		retryable, rejected, err := w.deliver(entries)
+		if written := len(entries) - len(retryable) - rejected; written > 0 {
+			w.batcher.stats.success(written)
+		}
+		if rejected > 0 {
+			w.batcher.stats.failure(rejected, err)
+		}
+		return retryable, err
+	}, spoolReplayBatches)
+}
+
+func (w *OpenSearchWriter) bulk(entries [][]byte) error {
+	if w.config.Rollover != RolloverNone {
+		if err := w.ensureWriteIndex(time.Now()); err != nil {
+			return err
+		}
+	}
+
+	action, err := json.Marshal(map[string]interface{}{
+		"index": map[string]string{"_index": w.config.Index},
+	})
 	if err != nil {
-		return 0, fmt.Errorf("failed to create HTTP request: %w", err)
+		return fmt.Errorf("failed to marshal bulk action: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	var body bytes.Buffer
+	for _, entry := range entries {
+		body.Write(action)
+		body.WriteByte('\n')
+		body.Write(bytes.TrimRight(entry, "\n"))
+		body.WriteByte('\n')
+	}
+
+	req, err := http.NewRequest("POST", w.config.Endpoint+"/_bulk", &body)
+	if err != nil {
+		return fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	w.authorize(req)
 
 	resp, err := w.client.Do(req)
 	if err != nil {
-		return 0, fmt.Errorf("failed to send log to OpenSearch: %w", err)
+		return fmt.Errorf("failed to send logs to OpenSearch: %w", err)
 	}
 	defer resp.Body.Close()
 
+	if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusTooManyRequests {
+		return fmt.Errorf("%w: status code %d", errOpenSearchRejected, resp.StatusCode)
+	}
+
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return 0, fmt.Errorf("received unexpected status code from OpenSearch: %d", resp.StatusCode)
+		return fmt.Errorf("received unexpected status code from OpenSearch: %d", resp.StatusCode)
 	}
 
-	return len(p), nil
+	return checkBulkResponse(resp.Body, entries)
+}
+
+// bulkItemsError is returned when some of the entries of a bulk request
+// failed. The ones that failed with 429 or a 5xx status can be sent again.
+type bulkItemsError struct {
+	retryable [][]byte
+	rejected  int
+	total     int
+	reason    string
+}
+
+func (e *bulkItemsError) Error() string {
+	return fmt.Sprintf("%d of %d logs failed, %d can be retried (%s)", len(e.retryable)+e.rejected, e.total, len(e.retryable), e.reason)
+}
+
+func retryableStatus(status int) bool {
+	return status == http.StatusTooManyRequests || status >= 500
+}
+
+// checkBulkResponse matches the items of a bulk response to entries, which
+// they follow in order.
+func checkBulkResponse(r io.Reader, entries [][]byte) error {
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  struct {
+				Type   string `json:"type"`
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(r).Decode(&result); err != nil {
+		return fmt.Errorf("failed to decode OpenSearch bulk response: %w", err)
+	}
+
+	if !result.Errors {
+		return nil
+	}
+	if len(result.Items) != len(entries) {
+		return fmt.Errorf("OpenSearch bulk response has %d items for %d logs", len(result.Items), len(entries))
+	}
+
+	failed := &bulkItemsError{total: len(entries)}
+	for i, item := range result.Items {
+		for _, op := range item {
+			if op.Status < 300 {
+				continue
+			}
+			if retryableStatus(op.Status) {
+				failed.retryable = append(failed.retryable, entries[i])
+			} else {
+				failed.rejected++
+			}
+			if failed.reason == "" {
+				failed.reason = fmt.Sprintf("%d %s: %s", op.Status, op.Error.Type, op.Error.Reason)
+			}
+		}
+	}
+
+	if len(failed.retryable) == 0 && failed.rejected == 0 {
+		return nil
+	}
+	return failed
 }
 
-func openSearchLoggercore() zapcore.Core {
-	writer := NewOpenSearchWriter(fmt.Sprintf("%s/%s/_doc", os.Getenv("OPEN_SEARCH_ENDPOINT"), os.Getenv("OPEN_SEARCH_INDEX_NAME")))
+func (i *instance) openSearchLoggercore(config OpenSearchConfig) (zapcore.Core, error) {
+	if config.Format == "" {
+		config.Format = i.sinkFormat(SinkOpenSearch)
+	}
+
+	writer, err := newOpenSearchWriter(config, i.sinkErrorReporter(SinkOpenSearch))
+	if err != nil {
+		return nil, err
+	}
+	i.onClose(writer.Close)
 
-	return zapcore.NewCore(
-		zapcore.NewJSONEncoder(encoderConfig),
-		zapcore.AddSync(writer),
-		zap.InfoLevel,
+	encoder, err := newEncoder(SinkOpenSearch, writer.config.Format)
+	if err != nil {
+		return nil, err
+	}
+
+	core := i.newSinkCore(
+		SinkOpenSearch,
+		encoder,
+		writer,
 	)
+
+	if err := writer.SetupIndex(); err != nil {
+		return core, fmt.Errorf("failed to set up OpenSearch index: %w", err)
+	}
+
+	return core, nil
 }
@@ -0,0 +1,326 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	RolloverNone   = ""
+	RolloverDaily  = "daily"
+	RolloverWeekly = "weekly"
+)
+
+func (w *OpenSearchWriter) SetupIndex() error {
+	if err := w.putIndexTemplate(); err != nil {
+		return err
+	}
+
+	if w.config.Retention != "" {
+		if err := w.putRetentionPolicy(); err != nil {
+			return err
+		}
+	}
+
+	if w.config.Rollover == RolloverNone {
+		return w.createIndex(w.config.Index, nil)
+	}
+
+	return w.ensureWriteIndex(time.Now())
+}
+
+func (w *OpenSearchWriter) indexPattern() string {
+	if w.config.Rollover == RolloverNone {
+		return w.config.Index
+	}
+	return w.config.Index + "-*"
+}
+
+func (w *OpenSearchWriter) indexNameAt(t time.Time) string {
+	switch w.config.Rollover {
+	case RolloverDaily:
+		return fmt.Sprintf("%s-%s", w.config.Index, t.UTC().Format("2006.01.02"))
+	case RolloverWeekly:
+		t = t.UTC()
+		offset := (int(t.Weekday()) + 6) % 7 // Weeks start on Monday
+		return fmt.Sprintf("%s-%s", w.config.Index, t.AddDate(0, 0, -offset).Format("2006.01.02"))
+	default:
+		return w.config.Index
+	}
+}
+
+func (w *OpenSearchWriter) putIndexTemplate() error {
+	template := map[string]interface{}{
+		"index_patterns": []string{w.indexPattern()},
+		"template": map[string]interface{}{
+			"settings": map[string]interface{}{
+				"number_of_shards":   w.config.Shards,
+				"number_of_replicas": w.config.Replicas,
+			},
+			"mappings": map[string]interface{}{
+				"properties": openSearchMappings(w.config.Format),
+			},
+		},
+	}
+
+	status, body, err := w.request("PUT", "/_index_template/"+w.config.Index+"-template", template)
+	if err != nil {
+		return fmt.Errorf("error creating index template: %w", err)
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("unexpected response from OpenSearch creating index template: %d %s", status, body)
+	}
+
+	return nil
+}
+
+func openSearchMappings(format string) map[string]interface{} {
+	config := encoderConfigFor(format)
+
+	mappings := map[string]interface{}{}
+	for key, kind := range map[string]string{
+		config.TimeKey:       "date",
+		config.LevelKey:      "keyword",
+		config.NameKey:       "keyword",
+		config.MessageKey:    "text",
+		config.CallerKey:     "keyword",
+		config.FunctionKey:   "keyword",
+		config.StacktraceKey: "text",
+	} {
+		if key != "" {
+			mappings[key] = map[string]string{"type": kind}
+		}
+	}
+	if format == FormatECS {
+		mappings["ecs.version"] = map[string]string{"type": "keyword"}
+		mappings["log.origin.file.name"] = map[string]string{"type": "keyword"}
+		mappings["log.origin.file.line"] = map[string]string{"type": "integer"}
+	}
+
+	for _, key := range []string{
+		ServiceNameKey, ServiceVersionKey, ServiceEnvironmentKey, HostNameKey,
+		KubernetesNamespaceKey, KubernetesPodKey, KubernetesNodeKey,
+	} {
+		mappings[key] = map[string]string{"type": "keyword"}
+	}
+	mappings[ProcessPIDKey] = map[string]string{"type": "integer"}
+
+	return mappings
+}
+
+func (w *OpenSearchWriter) putRetentionPolicy() error {
+	path := "/_plugins/_ism/policies/" + w.config.Index + "-retention"
+	policy := map[string]interface{}{
+		"policy": map[string]interface{}{
+			"description":   fmt.Sprintf("Delete %s indices older than %s", w.config.Index, w.config.Retention),
+			"default_state": "hot",
+			"states": []interface{}{
+				map[string]interface{}{
+					"name":    "hot",
+					"actions": []interface{}{},
+					"transitions": []interface{}{
+						map[string]interface{}{
+							"state_name": "delete",
+							"conditions": map[string]string{"min_index_age": w.config.Retention},
+						},
+					},
+				},
+				map[string]interface{}{
+					"name":        "delete",
+					"actions":     []interface{}{map[string]interface{}{"delete": map[string]interface{}{}}},
+					"transitions": []interface{}{},
+				},
+			},
+			"ism_template": []interface{}{
+				map[string]interface{}{
+					"index_patterns": []string{w.indexPattern()},
+					"priority":       100,
+				},
+			},
+		},
+	}
+
+	status, body, err := w.request("PUT", path, policy)
+	if err != nil {
+		return fmt.Errorf("error creating ISM policy: %w", err)
+	}
+
+	if status == http.StatusConflict {
+		// The policy already exists: updating it requires its sequence number
+		var current struct {
+			SeqNo       int64 `json:"_seq_no"`
+			PrimaryTerm int64 `json:"_primary_term"`
+		}
+
+		status, body, err = w.request("GET", path, nil)
+		if err != nil {
+			return fmt.Errorf("error reading ISM policy: %w", err)
+		}
+		if status != http.StatusOK {
+			return fmt.Errorf("unexpected response from OpenSearch reading ISM policy: %d %s", status, body)
+		}
+		if err := json.Unmarshal(body, &current); err != nil {
+			return fmt.Errorf("error decoding ISM policy: %w", err)
+		}
+
+		status, body, err = w.request("PUT", fmt.Sprintf("%s?if_seq_no=%d&if_primary_term=%d", path, current.SeqNo, current.PrimaryTerm), policy)
+		if err != nil {
+			return fmt.Errorf("error updating ISM policy: %w", err)
+		}
+	}
+
+	if status != http.StatusOK && status != http.StatusCreated {
+		return fmt.Errorf("unexpected response from OpenSearch creating ISM policy: %d %s", status, body)
+	}
+
+	return nil
+}
+
+func (w *OpenSearchWriter) createIndex(name string, aliases map[string]interface{}) error {
+	index := map[string]interface{}{}
+	if aliases != nil {
+		index["aliases"] = aliases
+	}
+
+	status, body, err := w.request("PUT", "/"+name, index)
+	if err != nil {
+		return fmt.Errorf("error creating index %s: %w", name, err)
+	}
+
+	if status == http.StatusBadRequest && strings.Contains(string(body), "resource_already_exists_exception") {
+		return nil
+	}
+	if status != http.StatusOK && status != http.StatusCreated {
+		return fmt.Errorf("unexpected response from OpenSearch creating index %s: %d %s", name, status, body)
+	}
+
+	return nil
+}
+
+// ensureWriteIndex creates the rolling index for t, if needed, and makes it
+// the write index of the alias named after the configured index.
+func (w *OpenSearchWriter) ensureWriteIndex(t time.Time) error {
+	w.indexMu.Lock()
+	defer w.indexMu.Unlock()
+
+	name := w.indexNameAt(t)
+	if name == w.writeIndex {
+		return nil
+	}
+
+	alias := w.config.Index
+	if w.writeIndex == "" {
+		if err := w.checkAlias(alias); err != nil {
+			return err
+		}
+	}
+
+	if err := w.createIndex(name, map[string]interface{}{alias: map[string]interface{}{}}); err != nil {
+		return err
+	}
+
+	status, body, err := w.request("GET", "/_alias/"+alias, nil)
+	if err != nil {
+		return fmt.Errorf("error reading alias %s: %w", alias, err)
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("unexpected response from OpenSearch reading alias %s: %d %s", alias, status, body)
+	}
+
+	var current map[string]struct {
+		Aliases map[string]struct {
+			IsWriteIndex bool `json:"is_write_index"`
+		} `json:"aliases"`
+	}
+	if err := json.Unmarshal(body, &current); err != nil {
+		return fmt.Errorf("error decoding alias %s: %w", alias, err)
+	}
+
+	actions := []interface{}{
+		map[string]interface{}{
+			"add": map[string]interface{}{"index": name, "alias": alias, "is_write_index": true},
+		},
+	}
+	for index, state := range current {
+		if index != name && state.Aliases[alias].IsWriteIndex {
+			actions = append(actions, map[string]interface{}{
+				"add": map[string]interface{}{"index": index, "alias": alias, "is_write_index": false},
+			})
+		}
+	}
+
+	status, body, err = w.request("POST", "/_aliases", map[string]interface{}{"actions": actions})
+	if err != nil {
+		return fmt.Errorf("error updating alias %s: %w", alias, err)
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("unexpected response from OpenSearch updating alias %s: %d %s", alias, status, body)
+	}
+
+	w.writeIndex = name
+	return nil
+}
+
+// checkAlias fails when alias is a concrete index, as on deployments that
+// wrote to the index before rolling was enabled: OpenSearch does not allow an
+// alias with the name of an index.
+func (w *OpenSearchWriter) checkAlias(alias string) error {
+	status, body, err := w.request("GET", "/"+alias+"/_alias", nil)
+	if err != nil {
+		return fmt.Errorf("error reading index %s: %w", alias, err)
+	}
+	if status == http.StatusNotFound {
+		return nil
+	}
+	if status != http.StatusOK {
+		return fmt.Errorf("unexpected response from OpenSearch reading index %s: %d %s", alias, status, body)
+	}
+
+	var indices map[string]json.RawMessage
+	if err := json.Unmarshal(body, &indices); err != nil {
+		return fmt.Errorf("error decoding index %s: %w", alias, err)
+	}
+	if _, exists := indices[alias]; exists {
+		return fmt.Errorf("%w: %s is an index, rolling needs the name for the write alias: reindex it into %s or use another index name",
+			errOpenSearchRejected, alias, w.indexPattern())
+	}
+
+	return nil
+}
+
+func (w *OpenSearchWriter) request(method, path string, payload interface{}) (int, []byte, error) {
+	var reqBody io.Reader
+	if payload != nil {
+		data, err := json.Marshal(payload)
+		if err != nil {
+			return 0, nil, fmt.Errorf("error marshalling request: %w", err)
+		}
+		reqBody = bytes.NewBuffer(data)
+	}
+
+	req, err := http.NewRequest(method, w.config.Endpoint+path, reqBody)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error creating HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	w.authorize(req)
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, nil, fmt.Errorf("error sending HTTP request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, nil, fmt.Errorf("error reading HTTP response: %w", err)
+	}
+
+	return resp.StatusCode, body, nil
+}
@@ -0,0 +1,272 @@
+package logger
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// bulkServer answers the bulk requests with the responses in turn, the last
+// one repeated, and records their bodies.
+type bulkServer struct {
+	*httptest.Server
+	mu        sync.Mutex
+	bodies    []string
+	responses []string
+}
+
+func newBulkServer(t *testing.T, responses ...string) *bulkServer {
+	s := &bulkServer{responses: responses}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" || r.Header.Get("Content-Type") != "application/x-ndjson" {
+			t.Errorf("got %s %s with content type %q", r.Method, r.URL.Path, r.Header.Get("Content-Type"))
+		}
+		body, _ := io.ReadAll(r.Body)
+
+		s.mu.Lock()
+		response := s.responses[min(len(s.bodies), len(s.responses)-1)]
+		s.bodies = append(s.bodies, string(body))
+		s.mu.Unlock()
+
+		_, _ = io.WriteString(w, response)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *bulkServer) requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string{}, s.bodies...)
+}
+
+func TestOpenSearchWriterFlush(t *testing.T) {
+	server := newBulkServer(t, `{"errors":false}`)
+	var reported []error
+	writer, err := newOpenSearchWriter(OpenSearchConfig{
+		Endpoint: server.URL,
+		Index:    "logs",
+		Batch:    BatchConfig{Size: 10, FlushInterval: time.Hour},
+	}, func(err error) { reported = append(reported, err) })
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range []string{`{"message":"a"}` + "\n", `{"message":"b"}` + "\n"} {
+		if _, err := writer.Write([]byte(entry)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := server.requests(); len(got) != 0 {
+		t.Fatalf("got %d requests before the flush", len(got))
+	}
+
+	if err := writer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"index":{"_index":"logs"}}` + "\n" + `{"message":"a"}` + "\n" +
+		`{"index":{"_index":"logs"}}` + "\n" + `{"message":"b"}` + "\n"
+	if got := server.requests(); len(got) != 1 || got[0] != want {
+		t.Errorf("got requests %q, want one with\n%s", got, want)
+	}
+
+	if _, err := writer.Write([]byte(`{"message":"c"}`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if got := server.requests(); len(got) != 2 || !strings.Contains(got[1], `{"message":"c"}`) {
+		t.Errorf("got requests %q, want the pending entry sent on close", got)
+	}
+	if len(reported) > 0 {
+		t.Errorf("got errors %v", reported)
+	}
+}
+
+func TestOpenSearchWriterRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	writer, err := newOpenSearchWriter(OpenSearchConfig{Endpoint: server.URL, Index: "logs", MaxRetries: 3}, func(error) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+
+	if _, err := writer.Write([]byte(`{"message":"a"}`)); err != nil {
+		t.Fatal(err)
+	}
+	// A 4xx status is not retried
+	if err := writer.Sync(); err == nil || !strings.Contains(err.Error(), "status code 400") {
+		t.Errorf("got error %v, want the rejection", err)
+	}
+}
+
+func TestOpenSearchWriterRetriesFailedItems(t *testing.T) {
+	server := newBulkServer(t,
+		`{"errors":true,"items":[{"index":{"status":201}},{"index":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue full"}}}]}`,
+		`{"errors":false}`,
+	)
+	writer, err := newOpenSearchWriter(OpenSearchConfig{Endpoint: server.URL, Index: "logs", MaxRetries: 1}, func(error) {})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer writer.Close()
+
+	for _, entry := range []string{`{"message":"a"}`, `{"message":"b"}`} {
+		if _, err := writer.Write([]byte(entry)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := writer.Sync(); err != nil {
+		t.Fatal(err)
+	}
+
+	got := server.requests()
+	if len(got) != 2 || strings.Contains(got[1], `"a"`) || !strings.Contains(got[1], `"b"`) {
+		t.Errorf("got requests %q, want the second one to send only the failed entry", got)
+	}
+}
+
+func TestCheckBulkResponse(t *testing.T) {
+	entries := [][]byte{[]byte(`{"n":1}`), []byte(`{"n":2}`), []byte(`{"n":3}`)}
+
+	tests := []struct {
+		name      string
+		response  string
+		err       bool
+		retryable []string
+		rejected  int
+	}{
+		{
+			name:     "no errors",
+			response: `{"errors":false,"items":[{"index":{"status":201}},{"index":{"status":201}},{"index":{"status":201}}]}`,
+		},
+		{
+			name: "retryable",
+			response: `{"errors":true,"items":[{"index":{"status":201}},` +
+				`{"index":{"status":429,"error":{"type":"es_rejected_execution_exception","reason":"queue full"}}},` +
+				`{"index":{"status":503,"error":{"type":"unavailable_shards_exception","reason":"no shard"}}}]}`,
+			err:       true,
+			retryable: []string{`{"n":2}`, `{"n":3}`},
+		},
+		{
+			name: "rejected",
+			response: `{"errors":true,"items":[{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"bad field"}}},` +
+				`{"index":{"status":201}},{"index":{"status":201}}]}`,
+			err:      true,
+			rejected: 1,
+		},
+		{
+			name: "mixed",
+			response: `{"errors":true,"items":[{"index":{"status":400,"error":{"type":"mapper_parsing_exception","reason":"bad field"}}},` +
+				`{"index":{"status":500,"error":{"type":"exception","reason":"boom"}}},{"index":{"status":201}}]}`,
+			err:       true,
+			retryable: []string{`{"n":2}`},
+			rejected:  1,
+		},
+		{
+			name:     "errors without failed items",
+			response: `{"errors":true,"items":[{"index":{"status":201}},{"index":{"status":200}},{"index":{"status":201}}]}`,
+		},
+		{
+			name:     "item count mismatch",
+			response: `{"errors":true,"items":[{"index":{"status":429}}]}`,
+			err:      true,
+		},
+		{
+			name:     "invalid body",
+			response: `<html>`,
+			err:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkBulkResponse(strings.NewReader(tt.response), entries)
+			if (err != nil) != tt.err {
+				t.Fatalf("got error %v, want error %v", err, tt.err)
+			}
+
+			var items *bulkItemsError
+			if !errors.As(err, &items) {
+				if len(tt.retryable) > 0 || tt.rejected > 0 {
+					t.Fatalf("got %v, want a bulkItemsError", err)
+				}
+				return
+			}
+
+			var retryable []string
+			for _, entry := range items.retryable {
+				retryable = append(retryable, string(entry))
+			}
+			if strings.Join(retryable, ",") != strings.Join(tt.retryable, ",") {
+				t.Errorf("got retryable %v, want %v", retryable, tt.retryable)
+			}
+			if items.rejected != tt.rejected {
+				t.Errorf("got %d rejected, want %d", items.rejected, tt.rejected)
+			}
+			if items.total != len(entries) {
+				t.Errorf("got total %d, want %d", items.total, len(entries))
+			}
+		})
+	}
+}
+
+func TestRetryableStatus(t *testing.T) {
+	tests := []struct {
+		status int
+		want   bool
+	}{
+		{400, false},
+		{404, false},
+		{409, false},
+		{429, true},
+		{500, true},
+		{503, true},
+	}
+
+	for _, tt := range tests {
+		if got := retryableStatus(tt.status); got != tt.want {
+			t.Errorf("retryableStatus(%d) = %v, want %v", tt.status, got, tt.want)
+		}
+	}
+}
+
+func TestNewOpenSearchWriterConfig(t *testing.T) {
+	tests := []struct {
+		name   string
+		config OpenSearchConfig
+		err    string
+	}{
+		{name: "single index", config: OpenSearchConfig{Index: "logs"}},
+		{name: "retention with rollover", config: OpenSearchConfig{Index: "logs", Rollover: RolloverDaily, Retention: "30d"}},
+		{name: "retention without rollover", config: OpenSearchConfig{Index: "logs", Retention: "30d"}, err: "needs a daily or weekly rollover"},
+		{name: "unknown rollover", config: OpenSearchConfig{Index: "logs", Rollover: "monthly"}, err: `unknown OpenSearch index rollover "monthly"`},
+		{name: "unknown format", config: OpenSearchConfig{Index: "logs", Format: "logfmt"}, err: `unsupported OpenSearch log format "logfmt"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer, err := newOpenSearchWriter(tt.config, func(error) {})
+			if writer != nil {
+				defer writer.Close()
+			}
+
+			if tt.err == "" && err != nil {
+				t.Errorf("got error %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("got error %v, want %q", err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,413 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const otlpScope = "github.com/DeltaNicola/infralib/logger"
+
+var errOTLPRejected = errors.New("logs rejected by the OTLP collector")
+
+type OTLPConfig struct {
+	// Endpoint is the full URL of the logs endpoint, e.g.
+	// http://localhost:4318/v1/logs.
+	Endpoint   string
+	Headers    map[string]string
+	Resource   map[string]string
+	Batch      BatchConfig
+	MaxRetries int
+	// MaxRetryWait bounds the time spent waiting between the retries of an
+	// export, while the next batches wait in the queue.
+	MaxRetryWait time.Duration
+	Timeout      time.Duration
+	TLS          TLSConfig
+}
+
+// otlpConfigFromEnv follows the OTEL_* variables of the OpenTelemetry SDKs,
+// the signal specific ones taking precedence over the generic ones.
+func otlpConfigFromEnv() OTLPConfig {
+	endpoint := os.Getenv("OTEL_EXPORTER_OTLP_LOGS_ENDPOINT")
+	if endpoint == "" {
+		endpoint = strings.TrimSuffix(envString("OTEL_EXPORTER_OTLP_ENDPOINT", "http://localhost:4318"), "/") + "/v1/logs"
+	}
+
+	headers := parseKeyValues(os.Getenv("OTEL_EXPORTER_OTLP_HEADERS"))
+	for name, value := range parseKeyValues(os.Getenv("OTEL_EXPORTER_OTLP_LOGS_HEADERS")) {
+		headers[name] = value
+	}
+
+	resource := parseKeyValues(os.Getenv("OTEL_RESOURCE_ATTRIBUTES"))
+	if service := envString("OTEL_SERVICE_NAME", os.Getenv("SERVICE_NAME")); service != "" {
+		resource["service.name"] = service
+	}
+	if version := envString("SERVICE_VERSION", buildVersion()); version != "" {
+		resource["service.version"] = version
+	}
+	for attribute, name := range map[string]string{
+		"k8s.namespace.name": "POD_NAMESPACE",
+		"k8s.pod.name":       "POD_NAME",
+		"k8s.node.name":      "NODE_NAME",
+	} {
+		if value := os.Getenv(name); value != "" {
+			resource[attribute] = value
+		}
+	}
+	if _, exists := resource["host.name"]; !exists {
+		if hostname, err := os.Hostname(); err == nil {
+			resource["host.name"] = hostname
+		}
+	}
+
+	return OTLPConfig{
+		Endpoint:     endpoint,
+		Headers:      headers,
+		Resource:     resource,
+		Batch:        batchConfigFromEnv("OTLP"),
+		MaxRetries:   envInt("OTLP_MAX_RETRIES", 5),
+		MaxRetryWait: envDuration("OTLP_MAX_RETRY_WAIT", 10*time.Second),
+		Timeout:      envDuration("OTEL_EXPORTER_OTLP_TIMEOUT", 10*time.Second),
+		TLS: TLSConfig{
+			CAFile:   os.Getenv("OTEL_EXPORTER_OTLP_CERTIFICATE"),
+			CertFile: os.Getenv("OTEL_EXPORTER_OTLP_CLIENT_CERTIFICATE"),
+			KeyFile:  os.Getenv("OTEL_EXPORTER_OTLP_CLIENT_KEY"),
+		},
+	}
+}
+
+// parseKeyValues parses the k1=v1,k2=v2 lists used by the OTEL_* variables,
+// with URL encoded values.
+func parseKeyValues(value string) map[string]string {
+	values := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		name, value, ok := strings.Cut(pair, "=")
+		if !ok || strings.TrimSpace(name) == "" {
+			continue
+		}
+		if decoded, err := url.QueryUnescape(strings.TrimSpace(value)); err == nil {
+			value = decoded
+		}
+		values[strings.TrimSpace(name)] = strings.TrimSpace(value)
+	}
+	return values
+}
+
+func (i *instance) otlpLoggerCore(config OTLPConfig) (zapcore.Core, error) {
+	if config.Endpoint == "" {
+		return nil, fmt.Errorf("OTLP logs endpoint is not set")
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	if config.TLS.enabled() {
+		tlsConfig, err := config.TLS.build()
+		if err != nil {
+			return nil, err
+		}
+		transport.TLSClientConfig = tlsConfig
+	}
+
+	// The service metadata is sent once as resource attributes rather than
+	// with every record, Resource taking precedence.
+	resource := i.config.Service.resource()
+	for key, value := range config.Resource {
+		resource[key] = value
+	}
+	serviceKeys := map[string]bool{}
+	for _, field := range i.config.Service.fields() {
+		serviceKeys[field.Key] = true
+	}
+
+	exporter := &otlpExporter{
+		config: config,
+		client: &http.Client{
+			Timeout:   config.Timeout,
+			Transport: transport,
+		},
+		resource:    otlpAttributes(resource),
+		serviceKeys: serviceKeys,
+	}
+	exporter.batcher = newBatcher(SinkOTLP, config.Batch, exporter.export)
+	exporter.batcher.onError = i.sinkErrorReporter(SinkOTLP)
+	exporter.batcher.start()
+	i.onClose(func() error {
+		err := exporter.batcher.close()
+		exporter.client.CloseIdleConnections()
+		return err
+	})
+
+	return i.wrapSinkCore(SinkOTLP, &otlpCore{exporter: exporter}), nil
+}
+
+type otlpCore struct {
+	exporter *otlpExporter
+	fields   []zapcore.Field
+}
+
+func (c *otlpCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *otlpCore) With(fields []zapcore.Field) zapcore.Core {
+	core := &otlpCore{
+		exporter: c.exporter,
+		fields:   c.fields[:len(c.fields):len(c.fields)],
+	}
+	for _, field := range fields {
+		if !c.exporter.serviceKeys[field.Key] {
+			core.fields = append(core.fields, field)
+		}
+	}
+	return core
+}
+
+func (c *otlpCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checked.AddCore(entry, c)
+}
+
+func (c *otlpCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	record := otlpRecord(entry, append(c.fields[:len(c.fields):len(c.fields)], fields...))
+	if err := c.exporter.batcher.add(record); err != nil {
+		return fmt.Errorf("failed to queue log for OTLP: %w", err)
+	}
+
+	if entry.Level > zapcore.ErrorLevel {
+		// The process is about to panic or exit
+		return c.Sync()
+	}
+	return nil
+}
+
+func (c *otlpCore) Sync() error {
+	return c.exporter.batcher.sync()
+}
+
+// The types below follow the JSON encoding of the OTLP protobuf messages.
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpAnyValue struct {
+	StringValue *string         `json:"stringValue,omitempty"`
+	BoolValue   *bool           `json:"boolValue,omitempty"`
+	IntValue    *string         `json:"intValue,omitempty"` // int64 is a string in JSON
+	DoubleValue *float64        `json:"doubleValue,omitempty"`
+	ArrayValue  *otlpArrayValue `json:"arrayValue,omitempty"`
+	KvlistValue *otlpKvlist     `json:"kvlistValue,omitempty"`
+}
+
+type otlpArrayValue struct {
+	Values []otlpAnyValue `json:"values"`
+}
+
+type otlpKvlist struct {
+	Values []otlpKeyValue `json:"values"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano         string         `json:"timeUnixNano"`
+	ObservedTimeUnixNano string         `json:"observedTimeUnixNano"`
+	SeverityNumber       int            `json:"severityNumber"`
+	SeverityText         string         `json:"severityText"`
+	Body                 otlpAnyValue   `json:"body"`
+	Attributes           []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID              string         `json:"traceId,omitempty"`
+	SpanID               string         `json:"spanId,omitempty"`
+}
+
+// otlpRecord maps the fields to attributes, except trace_id and span_id, as
+// added by FromContext, which become the trace context of the record.
+func otlpRecord(entry zapcore.Entry, fields []zapcore.Field) otlpLogRecord {
+	record := otlpLogRecord{
+		TimeUnixNano:         strconv.FormatInt(entry.Time.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		SeverityNumber:       otlpSeverity(entry.Level),
+		SeverityText:         entry.Level.CapitalString(),
+		Body:                 otlpValue(entry.Message),
+	}
+
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range fields {
+		switch {
+		case field.Key == "trace_id" && field.Type == zapcore.StringType:
+			record.TraceID = field.String
+		case field.Key == "span_id" && field.Type == zapcore.StringType:
+			record.SpanID = field.String
+		default:
+			field.AddTo(encoder)
+		}
+	}
+
+	if entry.LoggerName != "" {
+		encoder.Fields["logger.name"] = entry.LoggerName
+	}
+	if entry.Caller.Defined {
+		encoder.Fields["code.filepath"] = entry.Caller.File
+		encoder.Fields["code.lineno"] = entry.Caller.Line
+		if entry.Caller.Function != "" {
+			encoder.Fields["code.function"] = entry.Caller.Function
+		}
+	}
+	if entry.Stack != "" {
+		encoder.Fields["exception.stacktrace"] = entry.Stack
+	}
+
+	record.Attributes = otlpAttributes(encoder.Fields)
+	return record
+}
+
+func otlpAttributes[V any](values map[string]V) []otlpKeyValue {
+	attributes := make([]otlpKeyValue, 0, len(values))
+	for key, value := range values {
+		attributes = append(attributes, otlpKeyValue{Key: key, Value: otlpValue(value)})
+	}
+	return attributes
+}
+
+func otlpValue(value interface{}) otlpAnyValue {
+	switch v := value.(type) {
+	case string:
+		return otlpAnyValue{StringValue: &v}
+	case bool:
+		return otlpAnyValue{BoolValue: &v}
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
+		s := fmt.Sprint(v)
+		return otlpAnyValue{IntValue: &s}
+	case float32:
+		f := float64(v)
+		return otlpAnyValue{DoubleValue: &f}
+	case float64:
+		return otlpAnyValue{DoubleValue: &v}
+	case json.Number:
+		if _, err := v.Int64(); err == nil {
+			s := v.String()
+			return otlpAnyValue{IntValue: &s}
+		}
+		f, _ := v.Float64()
+		return otlpAnyValue{DoubleValue: &f}
+	case time.Time:
+		s := v.Format(time.RFC3339Nano)
+		return otlpAnyValue{StringValue: &s}
+	case time.Duration:
+		s := v.String()
+		return otlpAnyValue{StringValue: &s}
+	case []interface{}:
+		values := make([]otlpAnyValue, len(v))
+		for i, item := range v {
+			values[i] = otlpValue(item)
+		}
+		return otlpAnyValue{ArrayValue: &otlpArrayValue{Values: values}}
+	case map[string]interface{}:
+		return otlpAnyValue{KvlistValue: &otlpKvlist{Values: otlpAttributes(v)}}
+	}
+
+	// Reflected values are turned into the closest JSON representation
+	data, err := json.Marshal(value)
+	if err != nil {
+		s := fmt.Sprint(value)
+		return otlpAnyValue{StringValue: &s}
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	var decoded interface{}
+	if err := decoder.Decode(&decoded); err != nil || decoded == nil {
+		s := string(data)
+		return otlpAnyValue{StringValue: &s}
+	}
+	return otlpValue(decoded)
+}
+
+func otlpSeverity(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return 5
+	case zapcore.InfoLevel:
+		return 9
+	case zapcore.WarnLevel:
+		return 13
+	case zapcore.ErrorLevel:
+		return 17
+	case zapcore.DPanicLevel:
+		return 19
+	default:
+		return 21
+	}
+}
+
+type otlpExporter struct {
+	config   OTLPConfig
+	client   *http.Client
+	batcher  *batcher[otlpLogRecord]
+	resource []otlpKeyValue
+	// serviceKeys are the keys of the ServiceInfo fields, left out of the
+	// record attributes since they are in the resource.
+	serviceKeys map[string]bool
+}
+
+func (e *otlpExporter) export(records []otlpLogRecord) error {
+	body, err := json.Marshal(map[string]interface{}{
+		"resourceLogs": []interface{}{
+			map[string]interface{}{
+				"resource": map[string]interface{}{"attributes": e.resource},
+				"scopeLogs": []interface{}{
+					map[string]interface{}{
+						"scope":      map[string]string{"name": otlpScope},
+						"logRecords": records,
+					},
+				},
+			},
+		},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal OTLP logs: %w", err)
+	}
+
+	err = retry(e.config.MaxRetries, e.config.MaxRetryWait, errOTLPRejected, func() (time.Duration, error) {
+		return e.send(body)
+	}, func() {
+		e.batcher.stats.retried.Add(uint64(len(records)))
+	})
+	if err != nil {
+		return fmt.Errorf("failed to export %d logs over OTLP: %w", len(records), err)
+	}
+
+	return nil
+}
+
+func (e *otlpExporter) send(body []byte) (time.Duration, error) {
+	req, err := http.NewRequest("POST", e.config.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, fmt.Errorf("failed to create HTTP request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range e.config.Headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusOK, http.StatusAccepted:
+		return 0, nil
+	case http.StatusTooManyRequests, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return retryAfter(resp), fmt.Errorf("received unexpected status code from the OTLP collector: %d", resp.StatusCode)
+	default:
+		return 0, fmt.Errorf("%w: status code %d", errOTLPRejected, resp.StatusCode)
+	}
+}
@@ -0,0 +1,89 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+func TestOTLPServiceResource(t *testing.T) {
+	bodies := make(chan []byte, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body json.RawMessage
+		json.NewDecoder(r.Body).Decode(&body)
+		bodies <- body
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	logger, err := New(
+		WithConsole(false),
+		WithService(ServiceInfo{Name: "billing", Version: "1.2.0", Hostname: "web-1"}),
+		WithOTLP(OTLPConfig{
+			Endpoint: server.URL,
+			Resource: map[string]string{"host.name": "node-7"},
+			Timeout:  time.Second,
+		}),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+	logger.With(zap.String("order", "A-1")).Info("Invoice Sent", zap.String(ServiceNameKey, "explicit"))
+	if err := Close(logger); err != nil {
+		t.Fatal(err)
+	}
+
+	var request struct {
+		ResourceLogs []struct {
+			Resource struct {
+				Attributes []otlpKeyValue `json:"attributes"`
+			} `json:"resource"`
+			ScopeLogs []struct {
+				LogRecords []otlpLogRecord `json:"logRecords"`
+			} `json:"scopeLogs"`
+		} `json:"resourceLogs"`
+	}
+	select {
+	case body := <-bodies:
+		if err := json.Unmarshal(body, &request); err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("no logs were exported")
+	}
+
+	attributes := func(values []otlpKeyValue) map[string]string {
+		m := map[string]string{}
+		for _, value := range values {
+			if value.Value.StringValue != nil {
+				m[value.Key] = *value.Value.StringValue
+			}
+		}
+		return m
+	}
+
+	resource := attributes(request.ResourceLogs[0].Resource.Attributes)
+	for key, want := range map[string]string{
+		"service.name":    "billing",
+		"service.version": "1.2.0",
+		"host.name":       "node-7",
+	} {
+		if resource[key] != want {
+			t.Errorf("got resource %s %q, want %q", key, resource[key], want)
+		}
+	}
+
+	record := attributes(request.ResourceLogs[0].ScopeLogs[0].LogRecords[0].Attributes)
+	for _, key := range []string{ServiceVersionKey, HostNameKey} {
+		if value, exists := record[key]; exists {
+			t.Errorf("got record attribute %s %q, want it only in the resource", key, value)
+		}
+	}
+	if record["order"] != "A-1" || record[ServiceNameKey] != "explicit" {
+		t.Errorf("got record attributes %v, want the fields of the entry", record)
+	}
+}
@@ -0,0 +1,344 @@
+package logger
+
+import (
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+const redactedValue = "[REDACTED]"
+
+var defaultRedactedFields = []string{
+	"password", "passwd", "secret", "token", "access_token", "refresh_token",
+	"api_key", "apikey", "authorization", "credentials", "private_key",
+}
+
+type RedactConfig struct {
+	Enabled bool
+	// Fields are field or map keys redacted regardless of their value,
+	// compared case insensitively.
+	Fields []string
+	// KeyPattern redacts every field or map key it matches.
+	KeyPattern *regexp.Regexp
+	// ValuePattern redacts the parts of string values it matches.
+	ValuePattern *regexp.Regexp
+	// Hash replaces values with a short SHA-256 instead of a fixed mask, so
+	// that equal values can still be correlated.
+	Hash bool
+}
+
+func redactConfigFromEnv() (RedactConfig, error) {
+	config := RedactConfig{
+		Enabled: envBool("LOG_REDACT", true),
+		Fields:  defaultRedactedFields,
+		Hash:    strings.EqualFold(os.Getenv("LOG_REDACT_MODE"), "hash"),
+	}
+
+	if fields, exists := os.LookupEnv("LOG_REDACT_FIELDS"); exists {
+		config.Fields = nil
+		for _, field := range strings.Split(fields, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				config.Fields = append(config.Fields, field)
+			}
+		}
+	}
+
+	if pattern := os.Getenv("LOG_REDACT_KEY_PATTERN"); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return config, fmt.Errorf("invalid LOG_REDACT_KEY_PATTERN: %w", err)
+		}
+		config.KeyPattern = re
+	}
+
+	if pattern := os.Getenv("LOG_REDACT_VALUE_PATTERN"); pattern != "" {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return config, fmt.Errorf("invalid LOG_REDACT_VALUE_PATTERN: %w", err)
+		}
+		config.ValuePattern = re
+	}
+
+	return config, nil
+}
+
+// redactor is safe for concurrent use. A nil redactor leaves everything as is.
+type redactor struct {
+	fields       map[string]struct{}
+	keyPattern   *regexp.Regexp
+	valuePattern *regexp.Regexp
+	hash         bool
+}
+
+func newRedactor(config RedactConfig) *redactor {
+	if !config.Enabled {
+		return nil
+	}
+
+	r := &redactor{
+		fields:       make(map[string]struct{}, len(config.Fields)),
+		keyPattern:   config.KeyPattern,
+		valuePattern: config.ValuePattern,
+		hash:         config.Hash,
+	}
+	for _, field := range config.Fields {
+		r.fields[strings.ToLower(field)] = struct{}{}
+	}
+
+	return r
+}
+
+func (r *redactor) sensitiveKey(key string) bool {
+	if _, exists := r.fields[strings.ToLower(key)]; exists {
+		return true
+	}
+	return r.keyPattern != nil && r.keyPattern.MatchString(key)
+}
+
+func (r *redactor) mask(value string) string {
+	if !r.hash {
+		return redactedValue
+	}
+	sum := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(sum[:8])
+}
+
+func (r *redactor) redactString(value string) string {
+	if r == nil {
+		return value
+	}
+
+	if len(value) > 1 && (value[0] == '{' || value[0] == '[') && json.Valid([]byte(value)) {
+		var decoded interface{}
+		if err := json.Unmarshal([]byte(value), &decoded); err == nil {
+			if redacted, changed := r.redactValue(reflect.ValueOf(decoded)); changed {
+				if data, err := json.Marshal(redacted); err == nil {
+					return string(data)
+				}
+			}
+		}
+	}
+
+	if r.valuePattern != nil {
+		return r.valuePattern.ReplaceAllStringFunc(value, r.mask)
+	}
+	return value
+}
+
+func (r *redactor) redactFields(fields []zapcore.Field) []zapcore.Field {
+	if r == nil {
+		return fields
+	}
+
+	var redacted []zapcore.Field
+	for i, field := range fields {
+		if replacement, changed := r.redactField(field); changed {
+			if redacted == nil {
+				redacted = make([]zapcore.Field, len(fields))
+				copy(redacted, fields)
+			}
+			redacted[i] = replacement
+		}
+	}
+
+	if redacted == nil {
+		return fields
+	}
+	return redacted
+}
+
+func (r *redactor) redactField(field zapcore.Field) (zapcore.Field, bool) {
+	if field.Type != zapcore.SkipType && r.sensitiveKey(field.Key) {
+		return zap.String(field.Key, r.mask(fieldValue(field))), true
+	}
+
+	switch field.Type {
+	case zapcore.StringType:
+		if value := r.redactString(field.String); value != field.String {
+			return zap.String(field.Key, value), true
+		}
+	case zapcore.ByteStringType:
+		original := string(field.Interface.([]byte))
+		if value := r.redactString(original); value != original {
+			return zap.String(field.Key, value), true
+		}
+	case zapcore.StringerType, zapcore.ErrorType:
+		original := fieldValue(field)
+		if value := r.redactString(original); value != original {
+			if field.Type == zapcore.ErrorType {
+				return zap.NamedError(field.Key, errors.New(value)), true
+			}
+			return zap.String(field.Key, value), true
+		}
+	case zapcore.ReflectType:
+		if value, changed := r.redactValue(reflect.ValueOf(field.Interface)); changed {
+			return zap.Any(field.Key, value), true
+		}
+	case zapcore.ObjectMarshalerType, zapcore.ArrayMarshalerType, zapcore.InlineMarshalerType:
+		encoder := zapcore.NewMapObjectEncoder()
+		field.AddTo(encoder)
+		value := reflect.ValueOf(encoder.Fields)
+		if field.Type != zapcore.InlineMarshalerType {
+			value = reflect.ValueOf(encoder.Fields[field.Key])
+		}
+		if redacted, changed := r.redactValue(value); changed {
+			if field.Type == zapcore.InlineMarshalerType {
+				return zap.Inline(mapMarshaler(redacted.(map[string]interface{}))), true
+			}
+			return zap.Any(field.Key, redacted), true
+		}
+	}
+
+	return field, false
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// redactValue walks maps, structs and slices looking for sensitive keys,
+// fields tagged `log:"redact"` and strings matching the value pattern. It only
+// rebuilds the parts of v that changed; structs are rebuilt as maps keyed by
+// their JSON names.
+func (r *redactor) redactValue(v reflect.Value) (interface{}, bool) {
+	if !v.IsValid() {
+		return nil, false
+	}
+
+	if customMarshaler(v.Type()) {
+		return v.Interface(), false
+	}
+
+	switch v.Kind() {
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return v.Interface(), false
+		}
+		if redacted, changed := r.redactValue(v.Elem()); changed {
+			return redacted, true
+		}
+		return v.Interface(), false
+
+	case reflect.String:
+		if value := r.redactString(v.String()); value != v.String() {
+			return value, true
+		}
+		return v.Interface(), false
+
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return v.Interface(), false
+		}
+
+		result := make(map[string]interface{}, v.Len())
+		changed := false
+		iter := v.MapRange()
+		for iter.Next() {
+			key := iter.Key().String()
+			if r.sensitiveKey(key) {
+				result[key] = r.mask(fmt.Sprint(iter.Value().Interface()))
+				changed = true
+				continue
+			}
+			value, valueChanged := r.redactValue(iter.Value())
+			result[key] = value
+			changed = changed || valueChanged
+		}
+		if !changed {
+			return v.Interface(), false
+		}
+		return result, true
+
+	case reflect.Struct:
+		result := make(map[string]interface{}, v.NumField())
+		changed := false
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			if !field.IsExported() {
+				continue
+			}
+
+			name, omitEmpty := jsonFieldName(field)
+			if name == "-" {
+				continue
+			}
+			if omitEmpty && v.Field(i).IsZero() {
+				continue
+			}
+
+			if field.Tag.Get("log") == "redact" || r.sensitiveKey(name) {
+				result[name] = r.mask(fmt.Sprint(v.Field(i).Interface()))
+				changed = true
+				continue
+			}
+			value, valueChanged := r.redactValue(v.Field(i))
+			result[name] = value
+			changed = changed || valueChanged
+		}
+		if !changed {
+			return v.Interface(), false
+		}
+		return result, true
+
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 {
+			return v.Interface(), false
+		}
+
+		result := make([]interface{}, v.Len())
+		changed := false
+		for i := 0; i < v.Len(); i++ {
+			value, valueChanged := r.redactValue(v.Index(i))
+			result[i] = value
+			changed = changed || valueChanged
+		}
+		if !changed {
+			return v.Interface(), false
+		}
+		return result, true
+	}
+
+	return v.Interface(), false
+}
+
+func customMarshaler(t reflect.Type) bool {
+	if t.Kind() != reflect.Pointer && t.Kind() != reflect.Interface {
+		t = reflect.PointerTo(t)
+	}
+	return t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType)
+}
+
+func jsonFieldName(field reflect.StructField) (string, bool) {
+	tag := field.Tag.Get("json")
+	if tag == "" {
+		return field.Name, false
+	}
+
+	name, options, _ := strings.Cut(tag, ",")
+	if name == "" {
+		name = field.Name
+	}
+	return name, strings.Contains(options, "omitempty")
+}
+
+type mapMarshaler map[string]interface{}
+
+func (m mapMarshaler) MarshalLogObject(encoder zapcore.ObjectEncoder) error {
+	for key, value := range m {
+		if err := encoder.AddReflected(key, value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
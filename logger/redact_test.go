@@ -0,0 +1,145 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strings"
+	"testing"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+type redactUser struct {
+	Name     string `json:"name"`
+	Password string `json:"password"`
+	PIN      string `json:"pin" log:"redact"`
+	Note     string `json:"note,omitempty"`
+}
+
+func encodeFields(fields []zapcore.Field) map[string]interface{} {
+	encoder := zapcore.NewMapObjectEncoder()
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+	return encoder.Fields
+}
+
+func TestRedactFields(t *testing.T) {
+	defaults := RedactConfig{Enabled: true, Fields: defaultRedactedFields}
+	patterns := RedactConfig{
+		Enabled:      true,
+		KeyPattern:   regexp.MustCompile(`(?i)^x-`),
+		ValuePattern: regexp.MustCompile(`\d{4}-\d{4}`),
+	}
+
+	tests := []struct {
+		name   string
+		config RedactConfig
+		field  zapcore.Field
+		want   interface{}
+	}{
+		{"sensitive key", defaults, zap.String("password", "hunter2"), redactedValue},
+		{"key case", defaults, zap.String("Authorization", "Bearer x"), redactedValue},
+		{"non string value", defaults, zap.Int("token", 42), redactedValue},
+		{"other key", defaults, zap.String("user", "alice"), "alice"},
+		{"key pattern", patterns, zap.String("X-Api", "v"), redactedValue},
+		{"value pattern", patterns, zap.String("card", "card 1234-5678 ok"), "card [REDACTED] ok"},
+		{"value pattern in error", patterns, zap.Error(errors.New("bad 1234-5678")), "bad [REDACTED]"},
+		{"json string", defaults, zap.String("body", `{"user":"a","password":"b"}`), `{"password":"[REDACTED]","user":"a"}`},
+		{
+			"map",
+			defaults,
+			zap.Any("headers", map[string]string{"secret": "s", "accept": "json"}),
+			map[string]interface{}{"secret": redactedValue, "accept": "json"},
+		},
+		{
+			"struct",
+			defaults,
+			zap.Any("user", redactUser{Name: "alice", Password: "p", PIN: "1234"}),
+			map[string]interface{}{"name": "alice", "password": redactedValue, "pin": redactedValue},
+		},
+		{
+			"slice",
+			defaults,
+			zap.Any("users", []redactUser{{Name: "bob", Password: "p"}}),
+			[]interface{}{map[string]interface{}{"name": "bob", "password": redactedValue, "pin": redactedValue}},
+		},
+		{"disabled", RedactConfig{Fields: defaultRedactedFields}, zap.String("password", "hunter2"), "hunter2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fields := newRedactor(tt.config).redactFields([]zapcore.Field{tt.field})
+			got := encodeFields(fields)[tt.field.Key]
+
+			if !equalJSON(t, got, tt.want) {
+				t.Errorf("got %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedactHash(t *testing.T) {
+	r := newRedactor(RedactConfig{Enabled: true, Fields: []string{"email"}, Hash: true})
+
+	first := encodeFields(r.redactFields([]zapcore.Field{zap.String("email", "a@example.com")}))["email"].(string)
+	second := encodeFields(r.redactFields([]zapcore.Field{zap.String("email", "a@example.com")}))["email"].(string)
+	other := encodeFields(r.redactFields([]zapcore.Field{zap.String("email", "b@example.com")}))["email"].(string)
+
+	if !strings.HasPrefix(first, "sha256:") || strings.Contains(first, "example") {
+		t.Fatalf("got %q, want a sha256 hash", first)
+	}
+	if first != second {
+		t.Errorf("equal values hashed to %q and %q", first, second)
+	}
+	if first == other {
+		t.Errorf("different values hashed to %q", first)
+	}
+}
+
+func TestRedactFieldsUnchanged(t *testing.T) {
+	fields := []zapcore.Field{zap.String("user", "alice"), zap.Int("attempt", 1)}
+
+	got := newRedactor(RedactConfig{Enabled: true, Fields: defaultRedactedFields}).redactFields(fields)
+	if &got[0] != &fields[0] {
+		t.Error("fields were copied without a change")
+	}
+}
+
+func TestSinkCoreRedaction(t *testing.T) {
+	i := newInstance(Config{Redact: RedactConfig{
+		Enabled:      true,
+		Fields:       defaultRedactedFields,
+		ValuePattern: regexp.MustCompile(`\d{4}-\d{4}`),
+	}})
+	logger := zap.New(i.memoryLoggerCore(10)).With(zap.String("token", "t0k3n"))
+
+	logger.Info("Charged card 1234-5678", zap.String("password", "hunter2"), zap.String("user", "alice"))
+
+	entries := i.memory.snapshot()
+	if len(entries) != 1 {
+		t.Fatalf("got %d entries", len(entries))
+	}
+	want := map[string]interface{}{"token": redactedValue, "password": redactedValue, "user": "alice"}
+	if entry := entries[0]; entry.Message != "Charged card [REDACTED]" || !equalJSON(t, entry.Fields, want) {
+		t.Errorf("got %q with fields %v", entry.Message, entry.Fields)
+	}
+}
+
+// equalJSON compares got and want as JSON, so that map[string]string and
+// map[string]interface{} values with the same content are equal.
+func equalJSON(t *testing.T, got, want interface{}) bool {
+	t.Helper()
+
+	gotJSON, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantJSON, err := json.Marshal(want)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(gotJSON) == string(wantJSON)
+}
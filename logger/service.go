@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"os"
+	"runtime/debug"
+	"strconv"
+
+	"go.uber.org/zap"
+)
+
+// Keys of the service metadata fields, following the Elastic Common Schema.
+const (
+	ServiceNameKey         = "service.name"
+	ServiceVersionKey      = "service.version"
+	ServiceEnvironmentKey  = "service.environment"
+	HostNameKey            = "host.name"
+	ProcessPIDKey          = "process.pid"
+	KubernetesNamespaceKey = "kubernetes.namespace"
+	KubernetesPodKey       = "kubernetes.pod.name"
+	KubernetesNodeKey      = "kubernetes.node.name"
+)
+
+// ServiceInfo is added to every entry, empty values are left out.
+type ServiceInfo struct {
+	Name        string
+	Version     string
+	Environment string
+	Hostname    string
+	Namespace   string
+	Pod         string
+	Node        string
+	PID         int
+}
+
+// serviceInfoFromEnv reads SERVICE_NAME, SERVICE_VERSION, ENVIRONMENT and the
+// POD_NAME, POD_NAMESPACE and NODE_NAME variables usually set through the
+// Kubernetes downward API. The name is left empty when SERVICE_NAME is not
+// set, the version defaults to the version or VCS revision in the build info.
+func serviceInfoFromEnv() ServiceInfo {
+	info := ServiceInfo{
+		Name:        os.Getenv("SERVICE_NAME"),
+		Version:     envString("SERVICE_VERSION", buildVersion()),
+		Environment: os.Getenv("ENVIRONMENT"),
+		Namespace:   os.Getenv("POD_NAMESPACE"),
+		Pod:         os.Getenv("POD_NAME"),
+		Node:        os.Getenv("NODE_NAME"),
+		PID:         os.Getpid(),
+	}
+
+	if hostname, err := os.Hostname(); err == nil {
+		info.Hostname = hostname
+	}
+
+	return info
+}
+
+func buildVersion() string {
+	build, ok := debug.ReadBuildInfo()
+	if !ok {
+		return ""
+	}
+	if build.Main.Version != "" && build.Main.Version != "(devel)" {
+		return build.Main.Version
+	}
+
+	var revision string
+	var modified bool
+	for _, setting := range build.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			revision = setting.Value
+		case "vcs.modified":
+			modified = setting.Value == "true"
+		}
+	}
+	if len(revision) > 12 {
+		revision = revision[:12]
+	}
+	if revision != "" && modified {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+func (s ServiceInfo) fields() []zap.Field {
+	var fields []zap.Field
+	for _, field := range []struct{ key, value string }{
+		{ServiceNameKey, s.Name},
+		{ServiceVersionKey, s.Version},
+		{ServiceEnvironmentKey, s.Environment},
+		{HostNameKey, s.Hostname},
+		{KubernetesNamespaceKey, s.Namespace},
+		{KubernetesPodKey, s.Pod},
+		{KubernetesNodeKey, s.Node},
+	} {
+		if field.value != "" {
+			fields = append(fields, zap.String(field.key, field.value))
+		}
+	}
+	if s.PID != 0 {
+		fields = append(fields, zap.Int(ProcessPIDKey, s.PID))
+	}
+	return fields
+}
+
+// resource returns the metadata as OpenTelemetry resource attributes.
+func (s ServiceInfo) resource() map[string]string {
+	resource := map[string]string{}
+	for key, value := range map[string]string{
+		"service.name":                s.Name,
+		"service.version":             s.Version,
+		"deployment.environment.name": s.Environment,
+		"host.name":                   s.Hostname,
+		"k8s.namespace.name":          s.Namespace,
+		"k8s.pod.name":                s.Pod,
+		"k8s.node.name":               s.Node,
+	} {
+		if value != "" {
+			resource[key] = value
+		}
+	}
+	if s.PID != 0 {
+		resource["process.pid"] = strconv.Itoa(s.PID)
+	}
+	return resource
+}
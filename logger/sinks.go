@@ -0,0 +1,93 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"go.uber.org/zap/zapcore"
+)
+
+const SinkKafka = "kafka"
+
+// SinkFactory creates the writer of a registered sink for every logger
+// enabling it. Writers implementing io.Closer are closed with the logger.
+type SinkFactory func() (zapcore.WriteSyncer, error)
+
+// AsyncSink is implemented by the writers of registered sinks that only queue
+// entries in Write. Their entries are counted as written when the sink reports
+// their delivery with ReportSinkSuccess rather than when Write returns.
+type AsyncSink interface {
+	zapcore.WriteSyncer
+	Async() bool
+}
+
+var (
+	sinkFactoriesMu sync.RWMutex
+	sinkFactories   = map[string]SinkFactory{}
+)
+
+// RegisterSink makes a sink provided by another package available to New,
+// which enables it when it is in Config.Sinks; InitLogger adds it there when
+// LOG_ON_<NAME> is true. Packages register their sinks from init, so they
+// have to be imported for the sink to exist.
+func RegisterSink(name string, factory SinkFactory) {
+	sinkFactoriesMu.Lock()
+	defer sinkFactoriesMu.Unlock()
+
+	sinkFactories[name] = factory
+}
+
+func registeredSinks() []string {
+	sinkFactoriesMu.RLock()
+	defer sinkFactoriesMu.RUnlock()
+
+	names := make([]string, 0, len(sinkFactories))
+	for name := range sinkFactories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (i *instance) registeredSinkCores(names []string) ([]zapcore.Core, []error) {
+	sinkFactoriesMu.RLock()
+	defer sinkFactoriesMu.RUnlock()
+
+	var cores []zapcore.Core
+	var errs []error
+	for _, name := range names {
+		factory, registered := sinkFactories[name]
+		if !registered {
+			err := fmt.Errorf("%s sink is not registered", name)
+			if name == SinkKafka {
+				err = fmt.Errorf("%w: import github.com/DeltaNicola/infralib/kafka", err)
+			}
+			errs = append(errs, err)
+			continue
+		}
+
+		out, err := factory()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to create %s sink: %w", name, err))
+			continue
+		}
+
+		if closer, ok := out.(io.Closer); ok {
+			i.onClose(closer.Close)
+		}
+		if async, ok := out.(AsyncSink); ok && async.Async() {
+			statsFor(name).async.Store(true)
+		}
+
+		encoder, err := i.sinkEncoder(name)
+		if err != nil {
+			errs = append(errs, err)
+		}
+
+		cores = append(cores, i.newSinkCore(name, encoder, out))
+	}
+
+	return cores, errs
+}
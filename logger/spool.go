@@ -0,0 +1,173 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const spoolFileExt = ".ndjson"
+
+type SpoolConfig struct {
+	Enabled bool
+	Dir     string
+	MaxSize int64 // In bytes
+}
+
+func spoolConfigFromEnv() SpoolConfig {
+	defaultDir := filepath.Join(filepath.Dir(envString("LOG_FILE_PATH", "./app.log")), "opensearch-spool")
+
+	return SpoolConfig{
+		Enabled: envBool("OPEN_SEARCH_SPOOL", false),
+		Dir:     envString("OPEN_SEARCH_SPOOL_DIR", defaultDir),
+		MaxSize: int64(envInt("OPEN_SEARCH_SPOOL_MAX_SIZE", 100)) * 1024 * 1024, // In MB
+	}
+}
+
+// spool keeps failed batches on disk, one file per batch, named so that
+// lexical order is write order. It is only used from the batcher goroutine.
+type spool struct {
+	dir     string
+	maxSize int64
+	seq     uint64
+}
+
+func newSpool(config SpoolConfig) (*spool, error) {
+	if err := os.MkdirAll(config.Dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create spool directory: %w", err)
+	}
+
+	return &spool{
+		dir:     config.Dir,
+		maxSize: config.MaxSize,
+	}, nil
+}
+
+func (s *spool) store(entries [][]byte) error {
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d%s", time.Now().UnixNano(), s.seq%1000000, spoolFileExt)
+
+	if err := s.write(filepath.Join(s.dir, name), entries); err != nil {
+		return err
+	}
+
+	return s.evict()
+}
+
+// write replaces the file at path with entries, through a temporary file so
+// that a crash never leaves half a batch.
+func (s *spool) write(path string, entries [][]byte) error {
+	var data bytes.Buffer
+	for _, entry := range entries {
+		data.Write(bytes.TrimRight(entry, "\n"))
+		data.WriteByte('\n')
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data.Bytes(), 0o644); err != nil {
+		return fmt.Errorf("failed to write spool file: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		return fmt.Errorf("failed to commit spool file: %w", err)
+	}
+	return nil
+}
+
+func (s *spool) pending() ([]os.DirEntry, error) {
+	dirEntries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read spool directory: %w", err)
+	}
+
+	var files []os.DirEntry
+	for _, dirEntry := range dirEntries {
+		if !dirEntry.IsDir() && strings.HasSuffix(dirEntry.Name(), spoolFileExt) {
+			files = append(files, dirEntry)
+		}
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name() < files[j].Name() })
+
+	return files, nil
+}
+
+// replay sends at most max spooled batches, oldest first. send returns the
+// entries to keep for a later attempt: replay then keeps only those in the
+// batch and stops, so that ordering is preserved. It returns the number of
+// batches still spooled.
+func (s *spool) replay(send func([][]byte) ([][]byte, error), max int) (int, error) {
+	files, err := s.pending()
+	if err != nil {
+		return 0, err
+	}
+
+	for i, file := range files {
+		if i == max {
+			return len(files) - i, nil
+		}
+
+		path := filepath.Join(s.dir, file.Name())
+
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return len(files) - i, fmt.Errorf("failed to read spool file: %w", err)
+		}
+
+		var entries [][]byte
+		for _, line := range bytes.Split(data, []byte("\n")) {
+			if len(line) > 0 {
+				entries = append(entries, line)
+			}
+		}
+
+		if retry, err := send(entries); len(retry) > 0 {
+			if len(retry) < len(entries) {
+				if writeErr := s.write(path, retry); writeErr != nil {
+					return len(files) - i, errors.Join(err, writeErr)
+				}
+			}
+			return len(files) - i, err
+		}
+
+		if err := os.Remove(path); err != nil {
+			return len(files) - i, fmt.Errorf("failed to remove spool file: %w", err)
+		}
+	}
+
+	return 0, nil
+}
+
+func (s *spool) evict() error {
+	if s.maxSize <= 0 {
+		return nil
+	}
+
+	files, err := s.pending()
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	sizes := make([]int64, len(files))
+	for i, file := range files {
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		sizes[i] = info.Size()
+		total += sizes[i]
+	}
+
+	for i := 0; i < len(files) && total > s.maxSize; i++ {
+		if err := os.Remove(filepath.Join(s.dir, files[i].Name())); err != nil {
+			return fmt.Errorf("failed to evict spool file: %w", err)
+		}
+		total -= sizes[i]
+	}
+
+	return nil
+}
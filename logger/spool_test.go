@@ -0,0 +1,150 @@
+package logger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func batch(entries ...string) [][]byte {
+	result := make([][]byte, len(entries))
+	for i, entry := range entries {
+		result[i] = []byte(entry)
+	}
+	return result
+}
+
+func joinBatch(entries [][]byte) string {
+	var parts []string
+	for _, entry := range entries {
+		parts = append(parts, string(entry))
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestSpoolReplay(t *testing.T) {
+	errUnavailable := errors.New("unavailable")
+
+	tests := []struct {
+		name    string
+		batches [][][]byte
+		max     int
+		// keep returns the entries of a batch that failed, by batch index
+		keep      map[int][][]byte
+		sent      []string
+		remaining int
+		spooled   []string
+	}{
+		{
+			name:    "all sent in order",
+			batches: [][][]byte{batch("a", "b"), batch("c"), batch("d")},
+			max:     10,
+			sent:    []string{"a,b", "c", "d"},
+		},
+		{
+			name:      "bounded",
+			batches:   [][][]byte{batch("a"), batch("b"), batch("c")},
+			max:       2,
+			sent:      []string{"a", "b"},
+			remaining: 1,
+			spooled:   []string{"c"},
+		},
+		{
+			name:      "stops at the first failure",
+			batches:   [][][]byte{batch("a"), batch("b"), batch("c")},
+			max:       10,
+			keep:      map[int][][]byte{1: batch("b")},
+			sent:      []string{"a", "b"},
+			remaining: 2,
+			spooled:   []string{"b", "c"},
+		},
+		{
+			name:      "keeps only the failed entries",
+			batches:   [][][]byte{batch("a", "b", "c"), batch("d")},
+			max:       10,
+			keep:      map[int][][]byte{0: batch("b")},
+			sent:      []string{"a,b,c"},
+			remaining: 2,
+			spooled:   []string{"b", "d"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, err := newSpool(SpoolConfig{Enabled: true, Dir: t.TempDir()})
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, entries := range tt.batches {
+				if err := s.store(entries); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			var sent []string
+			remaining, err := s.replay(func(entries [][]byte) ([][]byte, error) {
+				sent = append(sent, joinBatch(entries))
+				if keep, exists := tt.keep[len(sent)-1]; exists {
+					return keep, errUnavailable
+				}
+				return nil, nil
+			}, tt.max)
+
+			if len(tt.keep) > 0 && !errors.Is(err, errUnavailable) {
+				t.Errorf("got error %v, want %v", err, errUnavailable)
+			}
+			if len(tt.keep) == 0 && err != nil {
+				t.Errorf("got error %v", err)
+			}
+			if strings.Join(sent, "|") != strings.Join(tt.sent, "|") {
+				t.Errorf("sent %v, want %v", sent, tt.sent)
+			}
+			if remaining != tt.remaining {
+				t.Errorf("got %d remaining, want %d", remaining, tt.remaining)
+			}
+
+			if got := spooledBatches(t, s); strings.Join(got, "|") != strings.Join(tt.spooled, "|") {
+				t.Errorf("spooled %v, want %v", got, tt.spooled)
+			}
+		})
+	}
+}
+
+func TestSpoolEvict(t *testing.T) {
+	s, err := newSpool(SpoolConfig{Enabled: true, Dir: t.TempDir(), MaxSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, entry := range []string{"aaaa", "bbbb", "cccc"} {
+		if err := s.store(batch(entry)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Every file is 5 bytes, the oldest is evicted to stay within 10
+	if got := spooledBatches(t, s); strings.Join(got, "|") != "bbbb|cccc" {
+		t.Errorf("spooled %v, want [bbbb cccc]", got)
+	}
+}
+
+func spooledBatches(t *testing.T, s *spool) []string {
+	t.Helper()
+
+	files, err := s.pending()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var batches []string
+	for _, file := range files {
+		data, err := os.ReadFile(filepath.Join(s.dir, file.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		batches = append(batches, strings.ReplaceAll(strings.TrimSuffix(string(data), "\n"), "\n", ","))
+	}
+	return batches
+}
@@ -0,0 +1,249 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+// SinkStats counts the entries handled by a sink since the process started.
+// Written entries reached their destination; dropped ones were discarded
+// without being sent, e.g. because the queue was full; failed ones could not
+// be delivered; retried ones were sent again, or spooled to disk to be.
+type SinkStats struct {
+	Written       uint64     `json:"written"`
+	Dropped       uint64     `json:"dropped"`
+	Failed        uint64     `json:"failed"`
+	Retried       uint64     `json:"retried"`
+	LastError     string     `json:"last_error,omitempty"`
+	LastErrorAt   *time.Time `json:"last_error_at,omitempty"`
+	LastSuccessAt *time.Time `json:"last_success_at,omitempty"`
+}
+
+type sinkStats struct {
+	// async sinks count written entries when they are delivered rather than
+	// when they are queued.
+	async atomic.Bool
+
+	written atomic.Uint64
+	dropped atomic.Uint64
+	failed  atomic.Uint64
+	retried atomic.Uint64
+
+	mu            sync.Mutex
+	lastError     string
+	lastErrorAt   time.Time
+	lastSuccessAt time.Time
+}
+
+var (
+	statsMu sync.Mutex
+	stats   = map[string]*sinkStats{}
+)
+
+func statsFor(sink string) *sinkStats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	s, exists := stats[sink]
+	if !exists {
+		s = &sinkStats{}
+		stats[sink] = s
+	}
+	return s
+}
+
+func (s *sinkStats) success(entries int) {
+	s.written.Add(uint64(entries))
+
+	s.mu.Lock()
+	s.lastSuccessAt = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *sinkStats) failure(entries int, err error) {
+	if errors.Is(err, ErrQueueFull) {
+		s.dropped.Add(uint64(entries))
+	} else {
+		s.failed.Add(uint64(entries))
+	}
+
+	s.mu.Lock()
+	s.lastError = err.Error()
+	s.lastErrorAt = time.Now()
+	s.mu.Unlock()
+}
+
+func (s *sinkStats) snapshot() SinkStats {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot := SinkStats{
+		Written:   s.written.Load(),
+		Dropped:   s.dropped.Load(),
+		Failed:    s.failed.Load(),
+		Retried:   s.retried.Load(),
+		LastError: s.lastError,
+	}
+	if !s.lastErrorAt.IsZero() {
+		lastErrorAt := s.lastErrorAt
+		snapshot.LastErrorAt = &lastErrorAt
+	}
+	if !s.lastSuccessAt.IsZero() {
+		lastSuccessAt := s.lastSuccessAt
+		snapshot.LastSuccessAt = &lastSuccessAt
+	}
+	return snapshot
+}
+
+// Stats returns the counters of every sink that was set up.
+func Stats() map[string]SinkStats {
+	statsMu.Lock()
+	defer statsMu.Unlock()
+
+	result := make(map[string]SinkStats, len(stats))
+	for sink, s := range stats {
+		result[sink] = s.snapshot()
+	}
+	return result
+}
+
+// ReportSinkSuccess records the delivery of entries in an AsyncSink.
+func ReportSinkSuccess(sink string, entries int) {
+	statsFor(sink).success(entries)
+}
+
+// ReportSinkError records a delivery failure of entries in a sink provided
+// by another package and reports it on the error output.
+func ReportSinkError(sink string, entries int, err error) {
+	statsFor(sink).failure(entries, err)
+	reportSinkError(sink, err)
+}
+
+// reportSinkError reports err on the error output of the installed logger,
+// for sinks that are not built by New.
+func reportSinkError(sink string, err error) {
+	current().reportSinkError(sink, err)
+}
+
+func (i *instance) reportSinkError(sink string, err error) {
+	fmt.Fprintf(i.errorOutput, "%v %s log sink error: %v\n", time.Now(), sink, err)
+}
+
+func (i *instance) sinkErrorReporter(sink string) func(error) {
+	return func(err error) {
+		i.reportSinkError(sink, err)
+	}
+}
+
+// StatsHandler serves Stats as JSON.
+func StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(Stats())
+	})
+}
+
+// MetricsHandler serves Stats in the Prometheus text format.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		writeMetrics(w, Stats())
+	})
+}
+
+func writeMetrics(w io.Writer, all map[string]SinkStats) {
+	sinks := make([]string, 0, len(all))
+	for sink := range all {
+		sinks = append(sinks, sink)
+	}
+	sort.Strings(sinks)
+
+	metrics := []struct {
+		name, kind, help string
+		value            func(SinkStats) float64
+	}{
+		{"log_sink_entries_written_total", "counter", "Log entries delivered by the sink.",
+			func(s SinkStats) float64 { return float64(s.Written) }},
+		{"log_sink_entries_dropped_total", "counter", "Log entries discarded by the sink without being sent.",
+			func(s SinkStats) float64 { return float64(s.Dropped) }},
+		{"log_sink_entries_failed_total", "counter", "Log entries the sink failed to deliver.",
+			func(s SinkStats) float64 { return float64(s.Failed) }},
+		{"log_sink_entries_retried_total", "counter", "Log entries sent again or spooled for retry by the sink.",
+			func(s SinkStats) float64 { return float64(s.Retried) }},
+		{"log_sink_last_error_timestamp_seconds", "gauge", "Unix time of the last sink error, 0 if none.",
+			func(s SinkStats) float64 { return unixSeconds(s.LastErrorAt) }},
+		{"log_sink_last_success_timestamp_seconds", "gauge", "Unix time of the last successful sink write, 0 if none.",
+			func(s SinkStats) float64 { return unixSeconds(s.LastSuccessAt) }},
+	}
+
+	for _, metric := range metrics {
+		fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", metric.name, metric.help, metric.name, metric.kind)
+		for _, sink := range sinks {
+			fmt.Fprintf(w, "%s{sink=%q} %g\n", metric.name, sink, metric.value(all[sink]))
+		}
+	}
+}
+
+func unixSeconds(t *time.Time) float64 {
+	if t == nil {
+		return 0
+	}
+	return float64(t.UnixNano()) / 1e9
+}
+
+// rateLimitedOutput lets through at most limit writes per interval and
+// reports how many were suppressed with the next one let through.
+type rateLimitedOutput struct {
+	out      zapcore.WriteSyncer
+	limit    int
+	interval time.Duration
+
+	mu         sync.Mutex
+	start      time.Time
+	count      int
+	suppressed int
+}
+
+// RateLimitedErrorOutput wraps out, typically os.Stderr, to be used as
+// Config.ErrorOutput without flooding it when a sink keeps failing.
+func RateLimitedErrorOutput(out zapcore.WriteSyncer, limit int, interval time.Duration) zapcore.WriteSyncer {
+	return &rateLimitedOutput{out: out, limit: limit, interval: interval}
+}
+
+func (o *rateLimitedOutput) Write(p []byte) (int, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if now := time.Now(); now.Sub(o.start) >= o.interval {
+		o.start = now
+		o.count = 0
+	}
+
+	if o.limit > 0 && o.count >= o.limit {
+		o.suppressed++
+		return len(p), nil
+	}
+	o.count++
+
+	if o.suppressed > 0 {
+		fmt.Fprintf(o.out, "%v %d log sink errors suppressed\n", time.Now(), o.suppressed)
+		o.suppressed = 0
+	}
+	return o.out.Write(p)
+}
+
+func (o *rateLimitedOutput) Sync() error {
+	return o.out.Sync()
+}
+
+var defaultErrorOutput = RateLimitedErrorOutput(zapcore.Lock(os.Stderr), 10, time.Minute)
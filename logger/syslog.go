@@ -0,0 +1,366 @@
+package logger
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+var syslogFacilities = map[string]int{
+	"kern": 0, "user": 1, "mail": 2, "daemon": 3, "auth": 4, "syslog": 5,
+	"lpr": 6, "news": 7, "uucp": 8, "cron": 9, "authpriv": 10, "ftp": 11,
+	"local0": 16, "local1": 17, "local2": 18, "local3": 19,
+	"local4": 20, "local5": 21, "local6": 22, "local7": 23,
+}
+
+var localSyslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+const (
+	syslogWriteTimeout = 5 * time.Second
+	// After a failed dial the messages fail without dialing again for
+	// syslogMinRetryDelay, doubled on every failure up to syslogMaxRetryDelay.
+	syslogMinRetryDelay = time.Second
+	syslogMaxRetryDelay = 30 * time.Second
+)
+
+type SyslogConfig struct {
+	// Network is "udp", "tcp", "tls", "unix" or "unixgram". When empty the
+	// local syslog socket, like /dev/log, is used.
+	Network  string
+	Address  string
+	Facility string
+	AppName  string
+	TLS      TLSConfig
+	Batch    BatchConfig
+}
+
+func syslogConfigFromEnv() SyslogConfig {
+	return SyslogConfig{
+		Network:  strings.ToLower(os.Getenv("SYSLOG_NETWORK")),
+		Address:  os.Getenv("SYSLOG_ADDRESS"),
+		Facility: envString("SYSLOG_FACILITY", "local0"),
+		AppName:  envString("SYSLOG_APP_NAME", envString("SERVICE_NAME", filepath.Base(os.Args[0]))),
+		TLS:      tlsConfigFromEnv("SYSLOG"),
+		Batch:    batchConfigFromEnv("SYSLOG"),
+	}
+}
+
+func (i *instance) syslogLoggerCore(config SyslogConfig) (zapcore.Core, error) {
+	facility, exists := syslogFacilities[strings.ToLower(config.Facility)]
+	if !exists {
+		return nil, fmt.Errorf("unknown syslog facility %q", config.Facility)
+	}
+
+	switch config.Network {
+	case "", "udp", "tcp", "tls", "unix", "unixgram":
+	default:
+		return nil, fmt.Errorf("unknown syslog network %q", config.Network)
+	}
+
+	var tlsConfig *tls.Config
+	if config.Network == "tls" {
+		var err error
+		if tlsConfig, err = config.TLS.build(); err != nil {
+			return nil, err
+		}
+	}
+
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "-"
+	}
+
+	writer := &syslogWriter{
+		network:   config.Network,
+		address:   config.Address,
+		tlsConfig: tlsConfig,
+		facility:  facility,
+		hostname:  syslogHeaderValue(hostname, 255),
+		appName:   syslogHeaderValue(config.AppName, 48),
+		procID:    strconv.Itoa(os.Getpid()),
+	}
+
+	// The messages are written by the batcher goroutine, a slow or
+	// unreachable daemon does not hold up the callers.
+	batcher := newBatcher(SinkSyslog, config.Batch, writer.writeBatch)
+	batcher.onError = i.sinkErrorReporter(SinkSyslog)
+	batcher.start()
+	i.onClose(func() error {
+		err := batcher.close()
+		writer.close()
+		return err
+	})
+
+	encoder, encoderErr := i.sinkEncoder(SinkSyslog)
+	core := i.wrapSinkCore(SinkSyslog, &syslogCore{encoder: encoder, writer: writer, batcher: batcher})
+
+	// The connection is retried when writing, a daemon that is down at
+	// startup does not disable the sink.
+	writer.mu.Lock()
+	err = writer.reconnect()
+	writer.mu.Unlock()
+	if err != nil {
+		return core, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+
+	return core, encoderErr
+}
+
+type syslogCore struct {
+	encoder zapcore.Encoder
+	writer  *syslogWriter
+	batcher *batcher[[]byte]
+}
+
+func (c *syslogCore) Enabled(zapcore.Level) bool {
+	return true
+}
+
+func (c *syslogCore) With(fields []zapcore.Field) zapcore.Core {
+	encoder := c.encoder.Clone()
+	for _, field := range fields {
+		field.AddTo(encoder)
+	}
+	return &syslogCore{encoder: encoder, writer: c.writer, batcher: c.batcher}
+}
+
+func (c *syslogCore) Check(entry zapcore.Entry, checked *zapcore.CheckedEntry) *zapcore.CheckedEntry {
+	return checked.AddCore(entry, c)
+}
+
+func (c *syslogCore) Write(entry zapcore.Entry, fields []zapcore.Field) error {
+	buf, err := c.encoder.EncodeEntry(entry, fields)
+	if err != nil {
+		return err
+	}
+	defer buf.Free()
+
+	err = c.batcher.add(c.writer.format(entry, bytes.TrimRight(buf.Bytes(), "\n")))
+	if err != nil {
+		return fmt.Errorf("failed to queue log for syslog: %w", err)
+	}
+
+	if entry.Level > zapcore.ErrorLevel {
+		// The process is about to panic or exit
+		return c.Sync()
+	}
+	return nil
+}
+
+func (c *syslogCore) Sync() error {
+	return c.batcher.sync()
+}
+
+type syslogWriter struct {
+	network   string
+	address   string
+	tlsConfig *tls.Config
+	facility  int
+	hostname  string
+	appName   string
+	procID    string
+
+	mu         sync.Mutex
+	conn       net.Conn
+	stream     bool // conn is a stream, messages are framed with octet counting
+	closed     bool
+	retryAt    time.Time // no dial is attempted before retryAt
+	retryDelay time.Duration
+}
+
+// reconnect dials the daemon unless a dial failed less than retryDelay ago.
+func (w *syslogWriter) reconnect() error {
+	if now := time.Now(); now.Before(w.retryAt) {
+		return fmt.Errorf("syslog is unreachable, next attempt in %s", w.retryAt.Sub(now).Round(time.Millisecond))
+	}
+
+	if err := w.connect(); err != nil {
+		w.retryDelay = min(max(2*w.retryDelay, syslogMinRetryDelay), syslogMaxRetryDelay)
+		w.retryAt = time.Now().Add(w.retryDelay)
+		return err
+	}
+
+	w.retryDelay = 0
+	w.retryAt = time.Time{}
+	return nil
+}
+
+func (w *syslogWriter) connect() error {
+	w.disconnect()
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+
+	var conn net.Conn
+	var err error
+	switch w.network {
+	case "":
+		addresses := localSyslogSockets
+		if w.address != "" {
+			addresses = []string{w.address}
+		}
+		for _, address := range addresses {
+			for _, network := range []string{"unixgram", "unix"} {
+				if conn, err = dialer.Dial(network, address); err == nil {
+					w.conn = conn
+					w.stream = network == "unix"
+					return nil
+				}
+			}
+		}
+		return err
+	case "tls":
+		conn, err = tls.DialWithDialer(dialer, "tcp", w.address, w.tlsConfig)
+	default:
+		conn, err = dialer.Dial(w.network, w.address)
+	}
+	if err != nil {
+		return err
+	}
+
+	w.conn = conn
+	w.stream = w.network != "udp" && w.network != "unixgram"
+	return nil
+}
+
+func (w *syslogWriter) close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.closed = true
+	w.disconnect()
+	return nil
+}
+
+func (w *syslogWriter) disconnect() {
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+}
+
+// writeBatch writes the messages of a batch one by one.
+func (w *syslogWriter) writeBatch(messages [][]byte) error {
+	var written int
+	var err error
+	for _, message := range messages {
+		if writeErr := w.write(message); writeErr != nil {
+			err = writeErr
+			continue
+		}
+		written++
+	}
+
+	switch {
+	case err == nil:
+		return nil
+	case written == 0:
+		return err
+	default:
+		return &partialFlush{written: written, failed: len(messages) - written, err: err}
+	}
+}
+
+func (w *syslogWriter) write(message []byte) error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.closed {
+		return errors.New("syslog sink is closed")
+	}
+
+	// One attempt on the current connection, one on a fresh one. A failed
+	// or timed out write may have sent part of a message, the connection is
+	// dropped so that the next message does not follow a broken frame.
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			if err = w.reconnect(); err != nil {
+				break
+			}
+		}
+		if err = w.conn.SetWriteDeadline(time.Now().Add(syslogWriteTimeout)); err == nil {
+			if _, err = w.conn.Write(w.frame(message)); err == nil {
+				return nil
+			}
+		}
+		w.disconnect()
+	}
+
+	return fmt.Errorf("failed to write to syslog: %w", err)
+}
+
+// frame prefixes message with its length on stream connections, octet
+// counting as described by RFC 6587. Datagrams hold one message each.
+func (w *syslogWriter) frame(message []byte) []byte {
+	if !w.stream {
+		return message
+	}
+	return append([]byte(strconv.Itoa(len(message))+" "), message...)
+}
+
+// format builds an RFC 5424 message.
+func (w *syslogWriter) format(entry zapcore.Entry, msg []byte) []byte {
+	msgID := "-"
+	if entry.LoggerName != "" {
+		msgID = syslogHeaderValue(entry.LoggerName, 32)
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "<%d>1 %s %s %s %s %s - ",
+		w.facility*8+syslogSeverity(entry.Level),
+		entry.Time.Format("2006-01-02T15:04:05.000000Z07:00"),
+		w.hostname,
+		w.appName,
+		w.procID,
+		msgID,
+	)
+	buf.Write(msg)
+
+	return buf.Bytes()
+}
+
+func syslogSeverity(level zapcore.Level) int {
+	switch level {
+	case zapcore.DebugLevel:
+		return 7
+	case zapcore.InfoLevel:
+		return 6
+	case zapcore.WarnLevel:
+		return 4
+	case zapcore.ErrorLevel:
+		return 3
+	case zapcore.DPanicLevel, zapcore.PanicLevel:
+		return 2
+	case zapcore.FatalLevel:
+		return 1
+	default:
+		return 5
+	}
+}
+
+// syslogHeaderValue keeps only the printable ASCII allowed in header fields.
+func syslogHeaderValue(value string, max int) string {
+	var b strings.Builder
+	for _, r := range value {
+		if r > 32 && r < 127 {
+			b.WriteRune(r)
+		}
+		if b.Len() == max {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "-"
+	}
+	return b.String()
+}
@@ -0,0 +1,201 @@
+package logger
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+)
+
+func TestSyslogFormat(t *testing.T) {
+	writer := &syslogWriter{
+		facility: syslogFacilities["local0"],
+		hostname: "web-1",
+		appName:  "billing",
+		procID:   "4242",
+	}
+	at := time.Date(2024, 5, 1, 12, 30, 0, 123456000, time.UTC)
+
+	tests := []struct {
+		name  string
+		entry zapcore.Entry
+		msg   string
+		want  string
+	}{
+		{
+			name:  "info",
+			entry: zapcore.Entry{Level: zapcore.InfoLevel, Time: at},
+			msg:   `{"message":"started"}`,
+			want:  `<134>1 2024-05-01T12:30:00.123456Z web-1 billing 4242 - - {"message":"started"}`,
+		},
+		{
+			name:  "error with logger name",
+			entry: zapcore.Entry{Level: zapcore.ErrorLevel, Time: at, LoggerName: "payments.stripe"},
+			msg:   "failed",
+			want:  `<131>1 2024-05-01T12:30:00.123456Z web-1 billing 4242 payments.stripe - failed`,
+		},
+		{
+			name:  "debug with long logger name",
+			entry: zapcore.Entry{Level: zapcore.DebugLevel, Time: at, LoggerName: strings.Repeat("x", 40)},
+			msg:   "trace",
+			want:  `<135>1 2024-05-01T12:30:00.123456Z web-1 billing 4242 ` + strings.Repeat("x", 32) + ` - trace`,
+		},
+		{
+			name:  "time zone",
+			entry: zapcore.Entry{Level: zapcore.WarnLevel, Time: at.In(time.FixedZone("CEST", 2*60*60))},
+			msg:   "slow",
+			want:  `<132>1 2024-05-01T14:30:00.123456+02:00 web-1 billing 4242 - - slow`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(writer.format(tt.entry, []byte(tt.msg))); got != tt.want {
+				t.Errorf("got  %s\nwant %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyslogFrame(t *testing.T) {
+	tests := []struct {
+		name    string
+		stream  bool
+		message string
+		want    string
+	}{
+		{"datagram", false, "<134>1 hello", "<134>1 hello"},
+		{"stream", true, "<134>1 hello", "12 <134>1 hello"},
+		{"stream multibyte", true, "<134>1 è", "9 <134>1 è"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			writer := &syslogWriter{stream: tt.stream}
+			if got := string(writer.frame([]byte(tt.message))); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSyslogReconnectBackoff(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	writer := &syslogWriter{network: "tcp", address: address}
+	defer writer.close()
+
+	if err := writer.write([]byte("<134>1 a")); err == nil || strings.Contains(err.Error(), "next attempt") {
+		t.Fatalf("got error %v, want the dial error", err)
+	}
+	if writer.retryDelay != syslogMinRetryDelay {
+		t.Errorf("got retry delay %s, want %s", writer.retryDelay, syslogMinRetryDelay)
+	}
+
+	start := time.Now()
+	if err := writer.write([]byte("<134>1 b")); err == nil || !strings.Contains(err.Error(), "syslog is unreachable, next attempt in") {
+		t.Errorf("got error %v, want the write to fail without dialing", err)
+	}
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("a write during the backoff took %s", elapsed)
+	}
+
+	writer.retryAt = time.Time{}
+	writer.write([]byte("<134>1 c"))
+	if writer.retryDelay != 2*syslogMinRetryDelay {
+		t.Errorf("got retry delay %s after a second failure, want %s", writer.retryDelay, 2*syslogMinRetryDelay)
+	}
+
+	listener, err = net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+	received := make(chan string, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		line, _ := bufio.NewReader(conn).ReadString('d')
+		received <- line
+	}()
+
+	writer.address = listener.Addr().String()
+	writer.retryAt = time.Time{}
+	if err := writer.write([]byte("<134>1 d")); err != nil {
+		t.Fatalf("got error %v once the daemon is back", err)
+	}
+	if writer.retryDelay != 0 {
+		t.Errorf("got retry delay %s after a dial succeeded, want it reset", writer.retryDelay)
+	}
+	select {
+	case got := <-received:
+		if got != "8 <134>1 d" {
+			t.Errorf("got %q, want the framed message", got)
+		}
+	case <-time.After(5 * time.Second):
+		t.Error("the message was not received")
+	}
+}
+
+func TestSyslogWriteBatch(t *testing.T) {
+	writer := &syslogWriter{network: "tcp", address: "127.0.0.1:1"}
+	writer.close()
+
+	err := writer.writeBatch([][]byte{[]byte("a"), []byte("b")})
+	if err == nil || err.Error() != "syslog sink is closed" {
+		t.Errorf("got error %v, want the sink closed error", err)
+	}
+}
+
+func TestSyslogSeverity(t *testing.T) {
+	tests := []struct {
+		level zapcore.Level
+		want  int
+	}{
+		{zapcore.DebugLevel, 7},
+		{zapcore.InfoLevel, 6},
+		{zapcore.WarnLevel, 4},
+		{zapcore.ErrorLevel, 3},
+		{zapcore.DPanicLevel, 2},
+		{zapcore.PanicLevel, 2},
+		{zapcore.FatalLevel, 1},
+	}
+
+	for _, tt := range tests {
+		if got := syslogSeverity(tt.level); got != tt.want {
+			t.Errorf("syslogSeverity(%s) = %d, want %d", tt.level, got, tt.want)
+		}
+	}
+}
+
+func TestSyslogHeaderValue(t *testing.T) {
+	tests := []struct {
+		value string
+		max   int
+		want  string
+	}{
+		{"billing", 48, "billing"},
+		{"my app", 48, "myapp"},
+		{"caffè", 48, "caff"},
+		{"", 48, "-"},
+		{"   ", 48, "-"},
+		{"abcdef", 3, "abc"},
+	}
+
+	for _, tt := range tests {
+		if got := syslogHeaderValue(tt.value, tt.max); got != tt.want {
+			t.Errorf("syslogHeaderValue(%q, %d) = %q, want %q", tt.value, tt.max, got, tt.want)
+		}
+	}
+}
@@ -3,9 +3,15 @@ package webhook
 import (
 	"bytes"
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"time"
 )
 
+// client is shared by every message, so that connections are reused, and
+// bounds the time a send can take when Discord does not answer.
+var client = &http.Client{Timeout: 10 * time.Second}
+
 type DiscordMessage struct {
 	Title       string                `json:"title"`
 	Description string                `json:"description"`
@@ -31,6 +37,11 @@ func (m DiscordMessage) WithTitle(title string) DiscordMessage {
 	return m
 }
 
+func (m DiscordMessage) WithColor(color int) DiscordMessage {
+	m.Color = color
+	return m
+}
+
 func (m DiscordMessage) WithDescription(descritpion string) DiscordMessage {
 	m.Description = descritpion
 	return m
@@ -51,7 +62,7 @@ func (m DiscordMessage) SendDiscordEmbedWithFields() error {
 	embed := map[string]interface{}{
 		"title":       m.Title,
 		"description": m.Description,
-		"color":       0x3498db,
+		"color":       m.Color,
 		"fields":      m.Fields,
 	}
 
@@ -70,15 +81,14 @@ func (m DiscordMessage) SendDiscordEmbedWithFields() error {
 	}
 	req.Header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusNoContent {
-		return err
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code from Discord: %d", resp.StatusCode)
 	}
 
 	return nil